@@ -48,21 +48,30 @@ func (p *Program) String() string {
 }
 
 // let语句解析
+// let语句，const语句也使用该节点
 type LetStatement struct {
-	Token token.Token // token.Let 词法单元
-	Name  *Identifier // 标识符，为了减少AST中各种类型节点的数量，复用该节点
-	Value Expression  // 产生值的表达式
+	Token   token.Token // token.LET 或 token.CONST 词法单元
+	Name    *Identifier // 标识符，为了减少AST中各种类型节点的数量，复用该节点
+	Pattern Expression  // 解构模式 let [a, b] = arr; 此时Name为nil
+	Value   Expression  // 产生值的表达式
 }
 
 func (ls *LetStatement) statementNode() {}
 
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
 
+// Constant 是否为const声明
+func (ls *LetStatement) Constant() bool { return ls.Token.Type == token.CONST }
+
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
+	if ls.Pattern != nil {
+		out.WriteString(ls.Pattern.String())
+	} else {
+		out.WriteString(ls.Name.String())
+	}
 	out.WriteString(" = ")
 
 	if ls.Value != nil {
@@ -111,6 +120,58 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+// throw语句 throw "message";
+type ThrowStatement struct {
+	Token token.Token // throw词法单元
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode() {}
+
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+
+func (ts *ThrowStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ts.TokenLiteral() + " ")
+
+	if ts.Value != nil {
+		out.WriteString(ts.Value.String())
+	}
+
+	out.WriteString(";")
+
+	return out.String()
+}
+
+// 结构体声明 struct Point { x, y }
+type StructStatement struct {
+	Token  token.Token // struct词法单元
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+func (ss *StructStatement) statementNode() {}
+
+func (ss *StructStatement) TokenLiteral() string { return ss.Token.Literal }
+
+func (ss *StructStatement) String() string {
+	var out bytes.Buffer
+
+	fields := []string{}
+	for _, f := range ss.Fields {
+		fields = append(fields, f.String())
+	}
+
+	out.WriteString("struct ")
+	out.WriteString(ss.Name.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(fields, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
 // 表达式语句
 type ExpressionStatement struct {
 	Token      token.Token // 该表达式中的第一个词法单元
@@ -197,6 +258,16 @@ func (b *Boolean) String() string {
 	return b.Token.Literal
 }
 
+type NullLiteral struct {
+	Token token.Token
+}
+
+func (nl *NullLiteral) expressionNode() {}
+
+func (nl *NullLiteral) TokenLiteral() string { return nl.Token.Literal }
+
+func (nl *NullLiteral) String() string { return nl.Token.Literal }
+
 type IfExpression struct {
 	Token       token.Token
 	Condition   Expression
@@ -227,6 +298,7 @@ func (ie *IfExpression) String() string {
 type BlockStatement struct {
 	Token      token.Token
 	Statements []Statement
+	Rbrace     token.Token // 结束的}词法单元
 }
 
 func (bs *BlockStatement) expressionNode() {}
@@ -246,6 +318,8 @@ func (bs *BlockStatement) String() string {
 type FunctionLiteral struct {
 	Token      token.Token
 	Parameters []*Identifier
+	Defaults   map[string]Expression // 参数默认值 fn(a, b = 10)，键为参数名
+	Rest       *Identifier           // 剩余参数 fn(a, ...rest)
 	Body       *BlockStatement
 }
 
@@ -256,25 +330,40 @@ func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
-	params := []string{}
-
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
-	}
-
 	out.WriteString(fl.TokenLiteral())
 	out.WriteString("(")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(FormatParameters(fl.Parameters, fl.Defaults, fl.Rest))
 	out.WriteString(")")
 	out.WriteString(fl.Body.String())
 
 	return out.String()
 }
 
+// FormatParameters 输出参数列表，函数字面量和函数对象共用
+func FormatParameters(parameters []*Identifier, defaults map[string]Expression, rest *Identifier) string {
+	params := []string{}
+
+	for _, p := range parameters {
+		if def, ok := defaults[p.Value]; ok {
+			params = append(params, p.String()+" = "+def.String())
+		} else {
+			params = append(params, p.String())
+		}
+	}
+
+	if rest != nil {
+		params = append(params, "..."+rest.String())
+	}
+
+	return strings.Join(params, ", ")
+}
+
 type CallExpression struct {
-	Token     token.Token
-	Function  Expression // 标识符或函数字面量
-	Arguments []Expression
+	Token          token.Token
+	Function       Expression // 标识符或函数字面量
+	Arguments      []Expression
+	NamedArguments []*NamedArgument // 命名参数 f(b: 3)，位于位置参数之后
+	Rparen         token.Token      // 结束的)词法单元
 }
 
 func (ce *CallExpression) expressionNode() {}
@@ -289,6 +378,9 @@ func (ce *CallExpression) String() string {
 	for _, a := range ce.Arguments {
 		args = append(args, a.String())
 	}
+	for _, na := range ce.NamedArguments {
+		args = append(args, na.String())
+	}
 
 	out.WriteString(ce.Function.String())
 	out.WriteString("(")
@@ -298,6 +390,19 @@ func (ce *CallExpression) String() string {
 	return out.String()
 }
 
+// 调用时的命名参数
+type NamedArgument struct {
+	Token token.Token // 参数名词法单元
+	Name  *Identifier
+	Value Expression
+}
+
+func (na *NamedArgument) TokenLiteral() string { return na.Token.Literal }
+
+func (na *NamedArgument) String() string {
+	return na.Name.String() + ": " + na.Value.String()
+}
+
 type StringLiteral struct {
 	Token token.Token
 	Value string
@@ -312,6 +417,7 @@ func (sl *StringLiteral) String() string { return sl.Token.Literal }
 type ArrayLiteral struct {
 	Token    token.Token
 	Elements []Expression
+	Rbracket token.Token // 结束的]词法单元
 }
 
 func (al *ArrayLiteral) expressionNode() {}
@@ -354,10 +460,40 @@ func (ie *IndexExpression) String() string {
 	return out.String()
 }
 
+// 点运算 h.key，作为调用表达式的函数时表示方法调用 x.method(args)
+type DotExpression struct {
+	Token token.Token // .词法单元
+	Left  Expression
+	Name  *Identifier
+}
+
+func (de *DotExpression) expressionNode() {}
+
+func (de *DotExpression) TokenLiteral() string { return de.Token.Literal }
+
+func (de *DotExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(de.Left.String())
+	out.WriteString(".")
+	out.WriteString(de.Name.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 // 语法分析阶段，所有表达式都应该可以用做哈希字面量中的键和值
 type HashLiteral struct {
-	Token token.Token
-	Pairs map[Expression]Expression
+	Token  token.Token
+	Pairs  []*HashPair // 按源代码中的顺序保存
+	Rbrace token.Token // 结束的}词法单元
+}
+
+// 哈希字面量中的一个键值对
+type HashPair struct {
+	Key   Expression
+	Value Expression
 }
 
 func (hl *HashLiteral) expressionNode() {}
@@ -368,8 +504,8 @@ func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for key, val := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+val.String())
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+":"+pair.Value.String())
 	}
 
 	out.WriteString("{")
@@ -379,59 +515,162 @@ func (hl *HashLiteral) String() string {
 	return out.String()
 }
 
-type modifierFunc func(Node) Node
+// 数组解构模式 [a, [b, c], ...rest]
+type ArrayPattern struct {
+	Token    token.Token  // [ 词法单元
+	Elements []Expression // 标识符或嵌套的模式
+	Rest     *Identifier  // 接收剩余元素
+}
+
+func (ap *ArrayPattern) expressionNode() {}
+
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+
+func (ap *ArrayPattern) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range ap.Elements {
+		elements = append(elements, el.String())
+	}
+	if ap.Rest != nil {
+		elements = append(elements, "..."+ap.Rest.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// 哈希解构模式 {name, "age": a}
+// 简写形式{name}等同于{"name": name}
+type HashPattern struct {
+	Token  token.Token  // { 词法单元
+	Keys   []Expression // 键的字面量
+	Values []Expression // 与键一一对应的模式
+}
 
-// 只修改了子节点，没有修改父节点会导致String()输出不一致。
-// 实时创建新的词法单元，会丢失其来源信息
-func Modify(node Node, modifier modifierFunc) Node {
-	switch nodeT := node.(type) {
-	case *Program:
-		for i, statement := range nodeT.Statements {
-			nodeT.Statements[i], _ = Modify(statement, modifier).(Statement)
-		}
-	case *ExpressionStatement:
-		nodeT.Expression, _ = Modify(nodeT.Expression, modifier).(Expression)
-	case *InfixExpression:
-		nodeT.Left, _ = Modify(nodeT.Left, modifier).(Expression)
-		nodeT.Right, _ = Modify(nodeT.Right, modifier).(Expression)
-	case *PrefixExpression:
-		nodeT.Right, _ = Modify(nodeT.Right, modifier).(Expression)
-	case *IndexExpression:
-		nodeT.Left, _ = Modify(nodeT.Left, modifier).(Expression)
-		nodeT.Index, _ = Modify(nodeT.Index, modifier).(Expression)
-	case *IfExpression:
-		nodeT.Condition, _ = Modify(nodeT.Condition, modifier).(Expression)
-		nodeT.Consequence, _ = Modify(nodeT.Consequence, modifier).(*BlockStatement)
-		if nodeT.Alternative != nil {
-			nodeT.Alternative, _ = Modify(nodeT.Alternative, modifier).(*BlockStatement)
-		}
-	case *BlockStatement:
-		for i, _ := range nodeT.Statements {
-			nodeT.Statements[i], _ = Modify(nodeT.Statements[i], modifier).(Statement)
-		}
-	case *ReturnStatement:
-		nodeT.ReturnValue, _ = Modify(nodeT.ReturnValue, modifier).(Expression)
-	case *LetStatement:
-		nodeT.Value, _ = Modify(nodeT.Value, modifier).(Expression)
-	case *FunctionLiteral:
-		for i, _ := range nodeT.Parameters {
-			nodeT.Parameters[i], _ = Modify(nodeT.Parameters[i], modifier).(*Identifier)
-		}
-		nodeT.Body = Modify(nodeT.Body, modifier).(*BlockStatement)
-	case *ArrayLiteral:
-		for i, _ := range nodeT.Elements {
-			nodeT.Elements[i], _ = Modify(nodeT.Elements[i], modifier).(Expression)
-		}
-	case *HashLiteral:
-		newPairs := make(map[Expression]Expression)
-		for key, val := range nodeT.Pairs {
-			newKey, _ := Modify(key, modifier).(Expression)
-			newVal, _ := Modify(val, modifier).(Expression)
-			newPairs[newKey] = newVal
+func (hp *HashPattern) expressionNode() {}
+
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+
+func (hp *HashPattern) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for i, key := range hp.Keys {
+		value := hp.Values[i]
+		if isShorthand(key, value) {
+			pairs = append(pairs, value.String())
+		} else {
+			pairs = append(pairs, key.String()+": "+value.String())
 		}
-		nodeT.Pairs = newPairs
 	}
-	return modifier(node)
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// 简写形式的键由标识符词法单元生成
+func isShorthand(key, value Expression) bool {
+	str, ok := key.(*StringLiteral)
+	if !ok || str.Token.Type != token.IDENT {
+		return false
+	}
+	ident, ok := value.(*Identifier)
+	return ok && ident.Value == str.Value
+}
+
+// match表达式 match (value) { [x, y] => x + y, _ => 0 }
+type MatchExpression struct {
+	Token   token.Token // match 词法单元
+	Subject Expression
+	Arms    []*MatchArm
+	Rbrace  token.Token // 结束的}词法单元
+}
+
+func (me *MatchExpression) expressionNode() {}
+
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	out.WriteString("match (")
+	out.WriteString(me.Subject.String())
+	out.WriteString(") { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// try表达式 try { } catch (e) { } finally { }，catch和finally至少有一个
+type TryExpression struct {
+	Token      token.Token // try词法单元
+	Block      *BlockStatement
+	CatchParam *Identifier
+	Catch      *BlockStatement
+	Finally    *BlockStatement
+}
+
+func (te *TryExpression) expressionNode() {}
+
+func (te *TryExpression) TokenLiteral() string { return te.Token.Literal }
+
+func (te *TryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try ")
+	out.WriteString(te.Block.String())
+
+	if te.Catch != nil {
+		out.WriteString(" catch (")
+		out.WriteString(te.CatchParam.String())
+		out.WriteString(") ")
+		out.WriteString(te.Catch.String())
+	}
+
+	if te.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(te.Finally.String())
+	}
+
+	return out.String()
+}
+
+// match的一个分支，Guard为可选的if条件
+type MatchArm struct {
+	Token   token.Token // 模式的第一个词法单元
+	Pattern Expression
+	Guard   Expression
+	Body    Expression
+}
+
+func (ma *MatchArm) TokenLiteral() string { return ma.Token.Literal }
+
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ma.Pattern.String())
+	if ma.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(ma.Guard.String())
+	}
+	out.WriteString(" => ")
+	out.WriteString(ma.Body.String())
+
+	return out.String()
 }
 
 type MacroLiteral struct {
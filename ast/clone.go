@@ -0,0 +1,196 @@
+package ast
+
+// Clone 深拷贝节点，返回的AST与原来的AST不共享任何节点、切片和映射，
+// 修改拷贝(例如通过Modify)不会影响原来的AST
+func Clone(node Node) Node {
+	switch nodeT := node.(type) {
+	case *Program:
+		return &Program{Statements: cloneStatements(nodeT.Statements)}
+	case *ExpressionStatement:
+		return &ExpressionStatement{Token: nodeT.Token, Expression: cloneExpression(nodeT.Expression)}
+	case *LetStatement:
+		return &LetStatement{
+			Token:   nodeT.Token,
+			Name:    cloneIdentifier(nodeT.Name),
+			Pattern: cloneExpression(nodeT.Pattern),
+			Value:   cloneExpression(nodeT.Value),
+		}
+	case *ReturnStatement:
+		return &ReturnStatement{Token: nodeT.Token, ReturnValue: cloneExpression(nodeT.ReturnValue)}
+	case *ThrowStatement:
+		return &ThrowStatement{Token: nodeT.Token, Value: cloneExpression(nodeT.Value)}
+	case *StructStatement:
+		return &StructStatement{
+			Token:  nodeT.Token,
+			Name:   cloneIdentifier(nodeT.Name),
+			Fields: cloneIdentifiers(nodeT.Fields),
+		}
+	case *BlockStatement:
+		return cloneBlock(nodeT)
+	case *Identifier:
+		return cloneIdentifier(nodeT)
+	case *IntegerLiteral:
+		return &IntegerLiteral{Token: nodeT.Token, Value: nodeT.Value}
+	case *StringLiteral:
+		return &StringLiteral{Token: nodeT.Token, Value: nodeT.Value}
+	case *Boolean:
+		return &Boolean{Token: nodeT.Token, Value: nodeT.Value}
+	case *NullLiteral:
+		return &NullLiteral{Token: nodeT.Token}
+	case *PrefixExpression:
+		return &PrefixExpression{Token: nodeT.Token, Operator: nodeT.Operator, Right: cloneExpression(nodeT.Right)}
+	case *InfixExpression:
+		return &InfixExpression{
+			Token:    nodeT.Token,
+			Left:     cloneExpression(nodeT.Left),
+			Operator: nodeT.Operator,
+			Right:    cloneExpression(nodeT.Right),
+		}
+	case *IfExpression:
+		return &IfExpression{
+			Token:       nodeT.Token,
+			Condition:   cloneExpression(nodeT.Condition),
+			Consequence: cloneBlock(nodeT.Consequence),
+			Alternative: cloneBlock(nodeT.Alternative),
+		}
+	case *FunctionLiteral:
+		var defaults map[string]Expression
+		if nodeT.Defaults != nil {
+			defaults = make(map[string]Expression, len(nodeT.Defaults))
+			for name, def := range nodeT.Defaults {
+				defaults[name] = cloneExpression(def)
+			}
+		}
+		return &FunctionLiteral{
+			Token:      nodeT.Token,
+			Parameters: cloneIdentifiers(nodeT.Parameters),
+			Defaults:   defaults,
+			Rest:       cloneIdentifier(nodeT.Rest),
+			Body:       cloneBlock(nodeT.Body),
+		}
+	case *MacroLiteral:
+		return &MacroLiteral{
+			Token:      nodeT.Token,
+			Parameters: cloneIdentifiers(nodeT.Parameters),
+			Body:       cloneBlock(nodeT.Body),
+		}
+	case *CallExpression:
+		var named []*NamedArgument
+		if nodeT.NamedArguments != nil {
+			named = make([]*NamedArgument, len(nodeT.NamedArguments))
+			for i, arg := range nodeT.NamedArguments {
+				named[i], _ = Clone(arg).(*NamedArgument)
+			}
+		}
+		return &CallExpression{
+			Token:          nodeT.Token,
+			Function:       cloneExpression(nodeT.Function),
+			Arguments:      cloneExpressions(nodeT.Arguments),
+			NamedArguments: named,
+			Rparen:         nodeT.Rparen,
+		}
+	case *NamedArgument:
+		return &NamedArgument{Token: nodeT.Token, Name: cloneIdentifier(nodeT.Name), Value: cloneExpression(nodeT.Value)}
+	case *ArrayLiteral:
+		return &ArrayLiteral{Token: nodeT.Token, Elements: cloneExpressions(nodeT.Elements), Rbracket: nodeT.Rbracket}
+	case *IndexExpression:
+		return &IndexExpression{Token: nodeT.Token, Left: cloneExpression(nodeT.Left), Index: cloneExpression(nodeT.Index)}
+	case *DotExpression:
+		return &DotExpression{Token: nodeT.Token, Left: cloneExpression(nodeT.Left), Name: cloneIdentifier(nodeT.Name)}
+	case *HashLiteral:
+		var pairs []*HashPair
+		if nodeT.Pairs != nil {
+			pairs = make([]*HashPair, len(nodeT.Pairs))
+			for i, pair := range nodeT.Pairs {
+				pairs[i] = &HashPair{Key: cloneExpression(pair.Key), Value: cloneExpression(pair.Value)}
+			}
+		}
+		return &HashLiteral{Token: nodeT.Token, Pairs: pairs, Rbrace: nodeT.Rbrace}
+	case *ArrayPattern:
+		return &ArrayPattern{Token: nodeT.Token, Elements: cloneExpressions(nodeT.Elements), Rest: cloneIdentifier(nodeT.Rest)}
+	case *HashPattern:
+		return &HashPattern{Token: nodeT.Token, Keys: cloneExpressions(nodeT.Keys), Values: cloneExpressions(nodeT.Values)}
+	case *MatchExpression:
+		var arms []*MatchArm
+		if nodeT.Arms != nil {
+			arms = make([]*MatchArm, len(nodeT.Arms))
+			for i, arm := range nodeT.Arms {
+				arms[i], _ = Clone(arm).(*MatchArm)
+			}
+		}
+		return &MatchExpression{Token: nodeT.Token, Subject: cloneExpression(nodeT.Subject), Arms: arms, Rbrace: nodeT.Rbrace}
+	case *MatchArm:
+		return &MatchArm{
+			Token:   nodeT.Token,
+			Pattern: cloneExpression(nodeT.Pattern),
+			Guard:   cloneExpression(nodeT.Guard),
+			Body:    cloneExpression(nodeT.Body),
+		}
+	case *TryExpression:
+		return &TryExpression{
+			Token:      nodeT.Token,
+			Block:      cloneBlock(nodeT.Block),
+			CatchParam: cloneIdentifier(nodeT.CatchParam),
+			Catch:      cloneBlock(nodeT.Catch),
+			Finally:    cloneBlock(nodeT.Finally),
+		}
+	}
+	return node
+}
+
+func cloneExpression(exp Expression) Expression {
+	if exp == nil {
+		return nil
+	}
+	cloned, _ := Clone(exp).(Expression)
+	return cloned
+}
+
+func cloneIdentifier(ident *Identifier) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	return &Identifier{Token: ident.Token, Value: ident.Value}
+}
+
+func cloneBlock(block *BlockStatement) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	return &BlockStatement{Token: block.Token, Statements: cloneStatements(block.Statements), Rbrace: block.Rbrace}
+}
+
+func cloneStatements(statements []Statement) []Statement {
+	if statements == nil {
+		return nil
+	}
+	cloned := make([]Statement, len(statements))
+	for i, statement := range statements {
+		if statement != nil {
+			cloned[i], _ = Clone(statement).(Statement)
+		}
+	}
+	return cloned
+}
+
+func cloneExpressions(exps []Expression) []Expression {
+	if exps == nil {
+		return nil
+	}
+	cloned := make([]Expression, len(exps))
+	for i, exp := range exps {
+		cloned[i] = cloneExpression(exp)
+	}
+	return cloned
+}
+
+func cloneIdentifiers(idents []*Identifier) []*Identifier {
+	if idents == nil {
+		return nil
+	}
+	cloned := make([]*Identifier, len(idents))
+	for i, ident := range idents {
+		cloned[i] = cloneIdentifier(ident)
+	}
+	return cloned
+}
@@ -0,0 +1,72 @@
+package ast_test
+
+import (
+	"testing"
+
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+)
+
+func TestClone(t *testing.T) {
+	input := `
+let add = fn(a, b = 10, ...rest) { return a + b; };
+const [x, {"y": y}, ...others] = [1, {"y": 2}, 3];
+add(1, b: -2)[0].key;
+if (x > 1) { throw "big"; } else { null };
+struct Point { x, y }
+match (x) { 0 => "zero", [h, ...t] if len(t) > 0 => h, _ => true };
+try { 1 } catch (e) { e.message } finally { puts("done") };
+let m = macro(a) { quote(unquote(a) * 2); };
+`
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	cloned := ast.Clone(program)
+	if cloned.String() != program.String() {
+		t.Fatalf("clone differs.\nwant=%q\ngot=%q", program.String(), cloned.String())
+	}
+
+	// 拷贝与原来的AST不共享节点
+	original := make(map[ast.Node]bool)
+	ast.Inspect(program, func(node ast.Node) bool {
+		original[node] = true
+		return true
+	})
+	ast.Inspect(cloned, func(node ast.Node) bool {
+		if node != nil && original[node] {
+			t.Errorf("node shared between original and clone: %s", node.String())
+		}
+		return true
+	})
+
+	// 修改拷贝不影响原来的AST
+	before := program.String()
+	ast.Modify(cloned, func(node ast.Node) ast.Node {
+		if integer, ok := node.(*ast.IntegerLiteral); ok {
+			integer.Value = 42
+		}
+		if ident, ok := node.(*ast.Identifier); ok {
+			ident.Value = "renamed"
+		}
+		return node
+	})
+	if program.String() != before {
+		t.Errorf("modifying the clone changed the original.\nwant=%q\ngot=%q", before, program.String())
+	}
+}
+
+func TestCloneNil(t *testing.T) {
+	if ast.Clone(nil) != nil {
+		t.Errorf("Clone(nil) should be nil")
+	}
+
+	fn := &ast.FunctionLiteral{Body: &ast.BlockStatement{}}
+	cloned, ok := ast.Clone(fn).(*ast.FunctionLiteral)
+	if !ok || cloned == fn || cloned.Body == fn.Body || cloned.Rest != nil || cloned.Defaults != nil {
+		t.Errorf("wrong clone. got=%+v", cloned)
+	}
+}
@@ -0,0 +1,296 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 所有可以序列化的节点类型，键为Kind
+var nodeKinds = map[string]reflect.Type{}
+
+func init() {
+	for _, node := range []Node{
+		&Program{}, &LetStatement{}, &Identifier{}, &ReturnStatement{}, &ThrowStatement{},
+		&StructStatement{}, &ExpressionStatement{}, &IntegerLiteral{}, &PrefixExpression{},
+		&InfixExpression{}, &Boolean{}, &NullLiteral{}, &IfExpression{}, &BlockStatement{},
+		&FunctionLiteral{}, &CallExpression{}, &NamedArgument{}, &StringLiteral{},
+		&ArrayLiteral{}, &IndexExpression{}, &DotExpression{}, &HashLiteral{},
+		&ArrayPattern{}, &HashPattern{}, &MatchExpression{}, &MatchArm{},
+		&TryExpression{}, &MacroLiteral{},
+	} {
+		nodeKinds[Kind(node)] = reflect.TypeOf(node)
+	}
+}
+
+var nodeType = reflect.TypeOf((*Node)(nil)).Elem()
+
+// MarshalJSON 将AST序列化为JSON，每个节点是一个对象，kind为节点的类型名，
+// token中保存词法单元及其行列号，其余字段为节点字段名的小驼峰形式
+func MarshalJSON(node Node) ([]byte, error) {
+	return json.Marshal(encodeValue(reflect.ValueOf(&node).Elem()))
+}
+
+// UnmarshalJSON 从MarshalJSON生成的JSON还原AST
+func UnmarshalJSON(data []byte) (Node, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var raw interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	if raw == nil {
+		return nil, fmt.Errorf("expected node object, got null")
+	}
+	var node Node
+	if err := decodeValue(raw, reflect.ValueOf(&node).Elem()); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// 可以为空的子节点，其余的节点字段都必须存在，否则String()等方法会因为nil而panic
+var optionalFields = map[string]bool{
+	"LetStatement.Name":             true, // Name和Pattern二者之一
+	"LetStatement.Pattern":          true,
+	"IfExpression.Alternative":      true,
+	"FunctionLiteral.Defaults":      true,
+	"FunctionLiteral.Rest":          true,
+	"CallExpression.NamedArguments": true,
+	"ArrayPattern.Rest":             true,
+	"MatchArm.Guard":                true,
+	"TryExpression.CatchParam":      true, // 与Catch同时存在
+	"TryExpression.Catch":           true, // Catch和Finally至少有一个
+	"TryExpression.Finally":         true,
+}
+
+// 检查解码后的节点是否缺少必需的字段
+func checkFields(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.Ptr, reflect.Interface:
+		default:
+			continue
+		}
+		if field.IsNil() && !optionalFields[t.Name()+"."+t.Field(i).Name] {
+			return fmt.Errorf("%s.%s: missing required field", t.Name(), fieldName(t.Field(i).Name))
+		}
+	}
+
+	switch node := v.Addr().Interface().(type) {
+	case *LetStatement:
+		if (node.Name == nil) == (node.Pattern == nil) {
+			return fmt.Errorf("LetStatement: exactly one of name and pattern is required")
+		}
+	case *TryExpression:
+		if node.Catch == nil && node.Finally == nil {
+			return fmt.Errorf("TryExpression: catch or finally is required")
+		}
+		if (node.Catch == nil) != (node.CatchParam == nil) {
+			return fmt.Errorf("TryExpression: catchParam and catch must be given together")
+		}
+	case *HashPattern:
+		if len(node.Keys) != len(node.Values) {
+			return fmt.Errorf("HashPattern: keys and values have different lengths")
+		}
+	}
+	return nil
+}
+
+// 按字段顺序输出的JSON对象
+type jsonObject []jsonField
+
+type jsonField struct {
+	name  string
+	value interface{}
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(field.name)
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func encodeValue(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Ptr && v.Type().Implements(nodeType) {
+			return append(jsonObject{{"kind", Kind(v.Interface().(Node))}}, encodeFields(v.Elem())...)
+		}
+		return encodeValue(v.Elem())
+	case reflect.Struct:
+		return encodeFields(v)
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		elements := make([]interface{}, v.Len())
+		for i := range elements {
+			elements[i] = encodeValue(v.Index(i))
+		}
+		return elements
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		entries := make(map[string]interface{}, v.Len())
+		for _, key := range v.MapKeys() {
+			entries[key.String()] = encodeValue(v.MapIndex(key))
+		}
+		return entries
+	default:
+		return v.Interface()
+	}
+}
+
+func encodeFields(v reflect.Value) jsonObject {
+	fields := jsonObject{}
+	for i := 0; i < v.NumField(); i++ {
+		fields = append(fields, jsonField{fieldName(v.Type().Field(i).Name), encodeValue(v.Field(i))})
+	}
+	return fields
+}
+
+// ReturnValue -> returnValue
+func fieldName(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(r)) + name[size:]
+}
+
+func decodeValue(raw interface{}, v reflect.Value) error {
+	if raw == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		fields, ok := raw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected node object for %s, got %T", v.Type(), raw)
+		}
+		kind, _ := fields["kind"].(string)
+		t, ok := nodeKinds[kind]
+		if !ok {
+			return fmt.Errorf("unknown node kind %q", kind)
+		}
+		if !t.Implements(v.Type()) {
+			return fmt.Errorf("cannot use %s as %s", kind, v.Type().Name())
+		}
+		node := reflect.New(t.Elem())
+		if err := decodeFields(fields, node.Elem()); err != nil {
+			return err
+		}
+		v.Set(node)
+	case reflect.Ptr:
+		fields, ok := raw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected object for %s, got %T", v.Type(), raw)
+		}
+		if kind, ok := fields["kind"]; ok && kind != v.Type().Elem().Name() {
+			return fmt.Errorf("expected %s, got %v", v.Type().Elem().Name(), kind)
+		}
+		ptr := reflect.New(v.Type().Elem())
+		if err := decodeFields(fields, ptr.Elem()); err != nil {
+			return err
+		}
+		v.Set(ptr)
+	case reflect.Struct:
+		fields, ok := raw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected object for %s, got %T", v.Type(), raw)
+		}
+		return decodeFields(fields, v)
+	case reflect.Slice:
+		elements, ok := raw.([]interface{})
+		if !ok {
+			return fmt.Errorf("expected array for %s, got %T", v.Type(), raw)
+		}
+		slice := reflect.MakeSlice(v.Type(), len(elements), len(elements))
+		for i, element := range elements {
+			if element == nil {
+				return fmt.Errorf("null element at index %d", i)
+			}
+			if err := decodeValue(element, slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Map:
+		entries, ok := raw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected object for %s, got %T", v.Type(), raw)
+		}
+		m := reflect.MakeMapWithSize(v.Type(), len(entries))
+		for key, entry := range entries {
+			if entry == nil {
+				return fmt.Errorf("null value for key %q", key)
+			}
+			value := reflect.New(v.Type().Elem()).Elem()
+			if err := decodeValue(entry, value); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), value)
+		}
+		v.Set(m)
+	case reflect.String:
+		s, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("expected string, got %T", raw)
+		}
+		v.SetString(s)
+	case reflect.Int, reflect.Int64:
+		n, ok := raw.(json.Number)
+		if !ok {
+			return fmt.Errorf("expected number, got %T", raw)
+		}
+		i, err := n.Int64()
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Bool:
+		b, ok := raw.(bool)
+		if !ok {
+			return fmt.Errorf("expected bool, got %T", raw)
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("cannot decode %s", v.Type())
+	}
+	return nil
+}
+
+func decodeFields(fields map[string]interface{}, v reflect.Value) error {
+	for i := 0; i < v.NumField(); i++ {
+		name := fieldName(v.Type().Field(i).Name)
+		if err := decodeValue(fields[name], v.Field(i)); err != nil {
+			return fmt.Errorf("%s.%s: %w", v.Type().Name(), name, err)
+		}
+	}
+	return checkFields(v)
+}
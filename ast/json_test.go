@@ -0,0 +1,113 @@
+package ast_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	input := `
+let x = 5;
+const limit = -10 * (x + 2);
+let [a, b, ...others] = [1, 2, 3];
+let {"name": name} = {"name": "monkey", "age": 5};
+let add = fn(a, b = 10, ...rest) { return a + b; };
+add(1, b: 2);
+if (x > limit) { x } else { null };
+let h = {"key": [true, false]};
+h.key[0];
+struct Point { x, y }
+let describe = fn(v) {
+	match (v) {
+		0 => "zero",
+		[first, ...tail] if len(tail) > 0 => "list",
+		{"x": px} => px,
+		_ => "other",
+	}
+};
+try { throw "boom"; } catch (e) { e.message } finally { puts("done") };
+let m = macro(a) { quote(unquote(a) + 1); };
+"a,b".split(",");
+`
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	data, err := ast.MarshalJSON(program)
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %s", err)
+	}
+
+	node, err := ast.UnmarshalJSON(data)
+	if err != nil {
+		t.Fatalf("UnmarshalJSON failed: %s", err)
+	}
+
+	if node.String() != program.String() {
+		t.Errorf("String() differs after round trip.\nwant=%q\ngot=%q", program.String(), node.String())
+	}
+
+	again, err := ast.MarshalJSON(node)
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %s", err)
+	}
+	if !bytes.Equal(data, again) {
+		t.Errorf("JSON differs after round trip.\nwant=%s\ngot=%s", data, again)
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	program := parser.New(lexer.New("-x")).ParseProgram()
+
+	data, err := ast.MarshalJSON(program.Statements[0].(*ast.ExpressionStatement).Expression)
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %s", err)
+	}
+
+	expected := `{"kind":"PrefixExpression",` +
+		`"token":{"type":"-","literal":"-","line":1,"column":1},"operator":"-",` +
+		`"right":{"kind":"Identifier","token":{"type":"IDENT","literal":"x","line":1,"column":2},"value":"x"}}`
+	if string(data) != expected {
+		t.Errorf("wrong JSON.\nwant=%s\ngot=%s", expected, data)
+	}
+}
+
+func TestUnmarshalJSONErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"kind":"Unknown"}`, `unknown node kind "Unknown"`},
+		{`[1]`, "expected node object for ast.Node, got []interface {}"},
+		{`{"kind":"ExpressionStatement","expression":{"kind":"LetStatement"}}`,
+			"ExpressionStatement.expression: cannot use LetStatement as Expression"},
+		{`{"kind":"IntegerLiteral","value":"5"}`, "IntegerLiteral.value: expected number, got string"},
+		{`{"kind":"IfExpression","consequence":{"kind":"Identifier"}}`,
+			"IfExpression.consequence: expected BlockStatement, got Identifier"},
+		// 缺少必需的子节点
+		{`null`, "expected node object, got null"},
+		{`{"kind":"InfixExpression"}`, "InfixExpression.left: missing required field"},
+		{`{"kind":"InfixExpression","left":{"kind":"IntegerLiteral"}}`, "InfixExpression.right: missing required field"},
+		{`{"kind":"CallExpression","arguments":[]}`, "CallExpression.function: missing required field"},
+		{`{"kind":"FunctionLiteral","parameters":[]}`, "FunctionLiteral.body: missing required field"},
+		{`{"kind":"Program","statements":[null]}`, "Program.statements: null element at index 0"},
+		{`{"kind":"HashLiteral","pairs":[{"key":{"kind":"IntegerLiteral"}}]}`, "HashPair.value: missing required field"},
+		{`{"kind":"LetStatement","value":{"kind":"IntegerLiteral"}}`, "LetStatement: exactly one of name and pattern is required"},
+		{`{"kind":"TryExpression","block":{"kind":"BlockStatement"}}`, "TryExpression: catch or finally is required"},
+		{`{"kind":"HashPattern","keys":[{"kind":"StringLiteral"}],"values":[]}`, "HashPattern: keys and values have different lengths"},
+	}
+
+	for _, tt := range tests {
+		_, err := ast.UnmarshalJSON([]byte(tt.input))
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("wrong error for %s. want=%q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
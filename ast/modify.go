@@ -0,0 +1,124 @@
+package ast
+
+type modifierFunc func(Node) Node
+
+// Modify 后序遍历AST，先修改子节点，再用modifier的返回值替换节点本身
+// 只修改了子节点，没有修改父节点会导致String()输出不一致。
+// 实时创建新的词法单元，会丢失其来源信息
+func Modify(node Node, modifier modifierFunc) Node {
+	switch nodeT := node.(type) {
+	case *Program:
+		for i, statement := range nodeT.Statements {
+			nodeT.Statements[i], _ = Modify(statement, modifier).(Statement)
+		}
+	case *ExpressionStatement:
+		nodeT.Expression, _ = Modify(nodeT.Expression, modifier).(Expression)
+	case *InfixExpression:
+		nodeT.Left, _ = Modify(nodeT.Left, modifier).(Expression)
+		nodeT.Right, _ = Modify(nodeT.Right, modifier).(Expression)
+	case *PrefixExpression:
+		nodeT.Right, _ = Modify(nodeT.Right, modifier).(Expression)
+	case *IndexExpression:
+		nodeT.Left, _ = Modify(nodeT.Left, modifier).(Expression)
+		nodeT.Index, _ = Modify(nodeT.Index, modifier).(Expression)
+	case *DotExpression:
+		nodeT.Left, _ = Modify(nodeT.Left, modifier).(Expression)
+		nodeT.Name, _ = Modify(nodeT.Name, modifier).(*Identifier)
+	case *IfExpression:
+		nodeT.Condition, _ = Modify(nodeT.Condition, modifier).(Expression)
+		nodeT.Consequence, _ = Modify(nodeT.Consequence, modifier).(*BlockStatement)
+		if nodeT.Alternative != nil {
+			nodeT.Alternative, _ = Modify(nodeT.Alternative, modifier).(*BlockStatement)
+		}
+	case *BlockStatement:
+		for i := range nodeT.Statements {
+			nodeT.Statements[i], _ = Modify(nodeT.Statements[i], modifier).(Statement)
+		}
+	case *ReturnStatement:
+		nodeT.ReturnValue, _ = Modify(nodeT.ReturnValue, modifier).(Expression)
+	case *ThrowStatement:
+		nodeT.Value, _ = Modify(nodeT.Value, modifier).(Expression)
+	case *LetStatement:
+		if nodeT.Pattern != nil {
+			nodeT.Pattern, _ = Modify(nodeT.Pattern, modifier).(Expression)
+		} else {
+			nodeT.Name, _ = Modify(nodeT.Name, modifier).(*Identifier)
+		}
+		nodeT.Value, _ = Modify(nodeT.Value, modifier).(Expression)
+	case *StructStatement:
+		nodeT.Name, _ = Modify(nodeT.Name, modifier).(*Identifier)
+		for i := range nodeT.Fields {
+			nodeT.Fields[i], _ = Modify(nodeT.Fields[i], modifier).(*Identifier)
+		}
+	case *FunctionLiteral:
+		for i := range nodeT.Parameters {
+			nodeT.Parameters[i], _ = Modify(nodeT.Parameters[i], modifier).(*Identifier)
+		}
+		for name, def := range nodeT.Defaults {
+			nodeT.Defaults[name], _ = Modify(def, modifier).(Expression)
+		}
+		if nodeT.Rest != nil {
+			nodeT.Rest, _ = Modify(nodeT.Rest, modifier).(*Identifier)
+		}
+		nodeT.Body, _ = Modify(nodeT.Body, modifier).(*BlockStatement)
+	case *MacroLiteral:
+		for i := range nodeT.Parameters {
+			nodeT.Parameters[i], _ = Modify(nodeT.Parameters[i], modifier).(*Identifier)
+		}
+		nodeT.Body, _ = Modify(nodeT.Body, modifier).(*BlockStatement)
+	case *CallExpression:
+		nodeT.Function, _ = Modify(nodeT.Function, modifier).(Expression)
+		for i := range nodeT.Arguments {
+			nodeT.Arguments[i], _ = Modify(nodeT.Arguments[i], modifier).(Expression)
+		}
+		for i := range nodeT.NamedArguments {
+			nodeT.NamedArguments[i], _ = Modify(nodeT.NamedArguments[i], modifier).(*NamedArgument)
+		}
+	case *NamedArgument:
+		nodeT.Name, _ = Modify(nodeT.Name, modifier).(*Identifier)
+		nodeT.Value, _ = Modify(nodeT.Value, modifier).(Expression)
+	case *ArrayLiteral:
+		for i := range nodeT.Elements {
+			nodeT.Elements[i], _ = Modify(nodeT.Elements[i], modifier).(Expression)
+		}
+	case *HashLiteral:
+		// 键值对保存在切片中，修改后的键即使相同也不会互相覆盖
+		for _, pair := range nodeT.Pairs {
+			pair.Key, _ = Modify(pair.Key, modifier).(Expression)
+			pair.Value, _ = Modify(pair.Value, modifier).(Expression)
+		}
+	case *ArrayPattern:
+		for i := range nodeT.Elements {
+			nodeT.Elements[i], _ = Modify(nodeT.Elements[i], modifier).(Expression)
+		}
+		if nodeT.Rest != nil {
+			nodeT.Rest, _ = Modify(nodeT.Rest, modifier).(*Identifier)
+		}
+	case *HashPattern:
+		for i := range nodeT.Keys {
+			nodeT.Keys[i], _ = Modify(nodeT.Keys[i], modifier).(Expression)
+			nodeT.Values[i], _ = Modify(nodeT.Values[i], modifier).(Expression)
+		}
+	case *MatchExpression:
+		nodeT.Subject, _ = Modify(nodeT.Subject, modifier).(Expression)
+		for i := range nodeT.Arms {
+			nodeT.Arms[i], _ = Modify(nodeT.Arms[i], modifier).(*MatchArm)
+		}
+	case *MatchArm:
+		nodeT.Pattern, _ = Modify(nodeT.Pattern, modifier).(Expression)
+		if nodeT.Guard != nil {
+			nodeT.Guard, _ = Modify(nodeT.Guard, modifier).(Expression)
+		}
+		nodeT.Body, _ = Modify(nodeT.Body, modifier).(Expression)
+	case *TryExpression:
+		nodeT.Block, _ = Modify(nodeT.Block, modifier).(*BlockStatement)
+		if nodeT.Catch != nil {
+			nodeT.CatchParam, _ = Modify(nodeT.CatchParam, modifier).(*Identifier)
+			nodeT.Catch, _ = Modify(nodeT.Catch, modifier).(*BlockStatement)
+		}
+		if nodeT.Finally != nil {
+			nodeT.Finally, _ = Modify(nodeT.Finally, modifier).(*BlockStatement)
+		}
+	}
+	return modifier(node)
+}
@@ -92,6 +92,55 @@ func TestModify(t *testing.T) {
 			},
 		}},
 		{&ArrayLiteral{Elements: []Expression{one(), two()}}, &ArrayLiteral{Elements: []Expression{two(), two()}}},
+		{&CallExpression{
+			Function:       &Identifier{Value: "f"},
+			Arguments:      []Expression{one(), two()},
+			NamedArguments: []*NamedArgument{{Name: &Identifier{Value: "a"}, Value: one()}},
+		}, &CallExpression{
+			Function:       &Identifier{Value: "f"},
+			Arguments:      []Expression{two(), two()},
+			NamedArguments: []*NamedArgument{{Name: &Identifier{Value: "a"}, Value: two()}},
+		}},
+		{&DotExpression{Left: one(), Name: &Identifier{Value: "x"}}, &DotExpression{Left: two(), Name: &Identifier{Value: "x"}}},
+		{&ThrowStatement{Value: one()}, &ThrowStatement{Value: two()}},
+		{&LetStatement{Pattern: &ArrayPattern{Elements: []Expression{one()}}, Value: one()},
+			&LetStatement{Pattern: &ArrayPattern{Elements: []Expression{two()}}, Value: two()}},
+		{&HashPattern{Keys: []Expression{one()}, Values: []Expression{one()}},
+			&HashPattern{Keys: []Expression{two()}, Values: []Expression{two()}}},
+		{&FunctionLiteral{
+			Parameters: []*Identifier{{Value: "a"}},
+			Defaults:   map[string]Expression{"a": one()},
+			Body:       &BlockStatement{Statements: []Statement{}},
+		}, &FunctionLiteral{
+			Parameters: []*Identifier{{Value: "a"}},
+			Defaults:   map[string]Expression{"a": two()},
+			Body:       &BlockStatement{Statements: []Statement{}},
+		}},
+		{&MacroLiteral{
+			Parameters: []*Identifier{},
+			Body:       &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+		}, &MacroLiteral{
+			Parameters: []*Identifier{},
+			Body:       &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+		}},
+		{&MatchExpression{
+			Subject: one(),
+			Arms:    []*MatchArm{{Pattern: one(), Guard: one(), Body: one()}},
+		}, &MatchExpression{
+			Subject: two(),
+			Arms:    []*MatchArm{{Pattern: two(), Guard: two(), Body: two()}},
+		}},
+		{&TryExpression{
+			Block:      &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+			CatchParam: &Identifier{Value: "e"},
+			Catch:      &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+			Finally:    &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+		}, &TryExpression{
+			Block:      &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+			CatchParam: &Identifier{Value: "e"},
+			Catch:      &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+			Finally:    &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+		}},
 	}
 
 	for _, tt := range tests {
@@ -104,21 +153,24 @@ func TestModify(t *testing.T) {
 
 	// 对hash特殊处理
 	hashLiteral := &HashLiteral{
-		Pairs: map[Expression]Expression{
-			one(): one(),
-			two(): two(),
+		Pairs: []*HashPair{
+			{Key: one(), Value: one()},
+			{Key: two(), Value: two()},
 		},
 	}
 
 	Modify(hashLiteral, turnOneIntoTwo)
 
-	for keyT, valT := range hashLiteral.Pairs {
-		key, _ := keyT.(*IntegerLiteral)
+	if len(hashLiteral.Pairs) != 2 {
+		t.Fatalf("hash pairs collapsed. got=%d", len(hashLiteral.Pairs))
+	}
+	for _, pair := range hashLiteral.Pairs {
+		key, _ := pair.Key.(*IntegerLiteral)
 		if key.Value != 2 {
 			t.Errorf("value is not %d. got=%d", 2, key.Value)
 		}
 
-		val, _ := valT.(*IntegerLiteral)
+		val, _ := pair.Value.(*IntegerLiteral)
 		if val.Value != 2 {
 			t.Errorf("value is not %d. got=%d", 2, val.Value)
 		}
@@ -0,0 +1,165 @@
+package ast
+
+import "reflect"
+
+// Visitor Walk对每个节点调用Visit，返回的w不为nil时继续用w访问该节点的子节点，
+// 子节点访问完后再调用w.Visit(nil)
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk 按源代码中的顺序深度优先遍历AST，只读，不修改节点
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	for _, child := range Children(node) {
+		Walk(v, child)
+	}
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect 深度优先遍历AST，f返回true时继续访问子节点。子节点访问完后调用f(nil)
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// Kind 节点的类型名，例如CallExpression
+func Kind(node Node) string {
+	return reflect.TypeOf(node).Elem().Name()
+}
+
+// Children 节点的直接子节点，按源代码中的顺序，省略为nil的可选部分
+func Children(node Node) []Node {
+	var nodes []Node
+	add := func(n Node) {
+		if n != nil {
+			nodes = append(nodes, n)
+		}
+	}
+
+	switch nodeT := node.(type) {
+	case *Program:
+		for _, statement := range nodeT.Statements {
+			add(statement)
+		}
+	case *ExpressionStatement:
+		add(nodeT.Expression)
+	case *InfixExpression:
+		add(nodeT.Left)
+		add(nodeT.Right)
+	case *PrefixExpression:
+		add(nodeT.Right)
+	case *IndexExpression:
+		add(nodeT.Left)
+		add(nodeT.Index)
+	case *DotExpression:
+		add(nodeT.Left)
+		add(nodeT.Name)
+	case *IfExpression:
+		add(nodeT.Condition)
+		add(nodeT.Consequence)
+		if nodeT.Alternative != nil {
+			add(nodeT.Alternative)
+		}
+	case *BlockStatement:
+		for _, statement := range nodeT.Statements {
+			add(statement)
+		}
+	case *ReturnStatement:
+		add(nodeT.ReturnValue)
+	case *ThrowStatement:
+		add(nodeT.Value)
+	case *LetStatement:
+		if nodeT.Pattern != nil {
+			add(nodeT.Pattern)
+		} else {
+			add(nodeT.Name)
+		}
+		add(nodeT.Value)
+	case *StructStatement:
+		add(nodeT.Name)
+		for _, field := range nodeT.Fields {
+			add(field)
+		}
+	case *FunctionLiteral:
+		for _, param := range nodeT.Parameters {
+			add(param)
+			if def, ok := nodeT.Defaults[param.Value]; ok {
+				add(def)
+			}
+		}
+		if nodeT.Rest != nil {
+			add(nodeT.Rest)
+		}
+		add(nodeT.Body)
+	case *MacroLiteral:
+		for _, param := range nodeT.Parameters {
+			add(param)
+		}
+		add(nodeT.Body)
+	case *CallExpression:
+		add(nodeT.Function)
+		for _, arg := range nodeT.Arguments {
+			add(arg)
+		}
+		for _, arg := range nodeT.NamedArguments {
+			add(arg)
+		}
+	case *NamedArgument:
+		add(nodeT.Name)
+		add(nodeT.Value)
+	case *ArrayLiteral:
+		for _, el := range nodeT.Elements {
+			add(el)
+		}
+	case *HashLiteral:
+		for _, pair := range nodeT.Pairs {
+			add(pair.Key)
+			add(pair.Value)
+		}
+	case *ArrayPattern:
+		for _, el := range nodeT.Elements {
+			add(el)
+		}
+		if nodeT.Rest != nil {
+			add(nodeT.Rest)
+		}
+	case *HashPattern:
+		for i := range nodeT.Keys {
+			add(nodeT.Keys[i])
+			add(nodeT.Values[i])
+		}
+	case *MatchExpression:
+		add(nodeT.Subject)
+		for _, arm := range nodeT.Arms {
+			add(arm)
+		}
+	case *MatchArm:
+		add(nodeT.Pattern)
+		if nodeT.Guard != nil {
+			add(nodeT.Guard)
+		}
+		add(nodeT.Body)
+	case *TryExpression:
+		add(nodeT.Block)
+		if nodeT.Catch != nil {
+			add(nodeT.CatchParam)
+			add(nodeT.Catch)
+		}
+		if nodeT.Finally != nil {
+			add(nodeT.Finally)
+		}
+	}
+	return nodes
+}
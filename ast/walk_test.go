@@ -0,0 +1,124 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	// let f = fn(a, b = 1) { g(a, b: 2).x };
+	program := &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Name: &Identifier{Value: "f"},
+				Value: &FunctionLiteral{
+					Parameters: []*Identifier{{Value: "a"}, {Value: "b"}},
+					Defaults:   map[string]Expression{"b": &IntegerLiteral{Value: 1}},
+					Body: &BlockStatement{Statements: []Statement{
+						&ExpressionStatement{Expression: &DotExpression{
+							Left: &CallExpression{
+								Function:  &Identifier{Value: "g"},
+								Arguments: []Expression{&Identifier{Value: "a"}},
+								NamedArguments: []*NamedArgument{
+									{Name: &Identifier{Value: "b"}, Value: &IntegerLiteral{Value: 2}},
+								},
+							},
+							Name: &Identifier{Value: "x"},
+						}},
+					}},
+				},
+			},
+		},
+	}
+
+	var idents []string
+	var ints []int64
+	Inspect(program, func(node Node) bool {
+		switch nodeT := node.(type) {
+		case *Identifier:
+			idents = append(idents, nodeT.Value)
+		case *IntegerLiteral:
+			ints = append(ints, nodeT.Value)
+		}
+		return true
+	})
+
+	if !reflect.DeepEqual(idents, []string{"f", "a", "b", "g", "a", "b", "x"}) {
+		t.Errorf("wrong identifiers. got=%v", idents)
+	}
+	if !reflect.DeepEqual(ints, []int64{1, 2}) {
+		t.Errorf("wrong integers. got=%v", ints)
+	}
+
+	// 返回false时不访问子节点
+	var visited int
+	Inspect(program, func(node Node) bool {
+		if node != nil {
+			visited++
+		}
+		_, isFunction := node.(*FunctionLiteral)
+		return !isFunction
+	})
+	if visited != 4 {
+		t.Errorf("wrong number of visited nodes. want=4, got=%d", visited)
+	}
+}
+
+type depthVisitor struct {
+	depth int
+	max   *int
+}
+
+func (v depthVisitor) Visit(node Node) Visitor {
+	if node == nil {
+		return nil
+	}
+	if v.depth > *v.max {
+		*v.max = v.depth
+	}
+	return depthVisitor{depth: v.depth + 1, max: v.max}
+}
+
+func TestWalk(t *testing.T) {
+	// if (x) { [1, {2: 3}] } else { match (y) { _ => 4 } }
+	node := &IfExpression{
+		Condition: &Identifier{Value: "x"},
+		Consequence: &BlockStatement{Statements: []Statement{
+			&ExpressionStatement{Expression: &ArrayLiteral{Elements: []Expression{
+				&IntegerLiteral{Value: 1},
+				&HashLiteral{Pairs: []*HashPair{{Key: &IntegerLiteral{Value: 2}, Value: &IntegerLiteral{Value: 3}}}},
+			}}},
+		}},
+		Alternative: &BlockStatement{Statements: []Statement{
+			&ExpressionStatement{Expression: &MatchExpression{
+				Subject: &Identifier{Value: "y"},
+				Arms:    []*MatchArm{{Pattern: &Identifier{Value: "_"}, Body: &IntegerLiteral{Value: 4}}},
+			}},
+		}},
+	}
+
+	max := 0
+	Walk(depthVisitor{max: &max}, node)
+	// If -> Block -> ExpressionStatement -> Array -> Hash -> Integer
+	if max != 5 {
+		t.Errorf("wrong max depth. want=5, got=%d", max)
+	}
+}
+
+func TestKind(t *testing.T) {
+	tests := []struct {
+		node     Node
+		expected string
+	}{
+		{&Program{}, "Program"},
+		{&Identifier{Value: "x"}, "Identifier"},
+		{&InfixExpression{Operator: "+"}, "InfixExpression"},
+		{&MatchArm{}, "MatchArm"},
+	}
+
+	for _, tt := range tests {
+		if kind := Kind(tt.node); kind != tt.expected {
+			t.Errorf("wrong kind. want=%q, got=%q", tt.expected, kind)
+		}
+	}
+}
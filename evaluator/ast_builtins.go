@@ -0,0 +1,139 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+	"monkey/token"
+)
+
+// 在宏中检查和构造AST的内置函数，参数和返回值都是quote
+// ast_kind:节点的类型名，例如CallExpression
+// ast_children:节点的直接子节点组成的数组
+// ast_operator:前缀或中缀表达式的运算符
+// ast_value:字面量的值，标识符的名称
+// ast_ident:用名称构造标识符
+// ast_call:构造函数调用，参数可以是quote或者任意能转换为AST的值
+func (e *Evaluator) astBuiltins() map[string]*object.Builtin {
+	return map[string]*object.Builtin{
+		"ast_kind":     &object.Builtin{Fn: astKind},
+		"ast_children": &object.Builtin{Fn: astChildren},
+		"ast_operator": &object.Builtin{Fn: astOperator},
+		"ast_value":    &object.Builtin{Fn: astValue},
+		"ast_ident":    &object.Builtin{Fn: astIdent},
+		"ast_call":     &object.Builtin{Fn: e.astCall},
+	}
+}
+
+func astKind(args ...object.Object) object.Object {
+	if err := CheckArgs("ast_kind", args, object.QUOTE_OBJ); err != nil {
+		return err
+	}
+	return &object.String{Value: ast.Kind(args[0].(*object.Quote).Node)}
+}
+
+func astChildren(args ...object.Object) object.Object {
+	if err := CheckArgs("ast_children", args, object.QUOTE_OBJ); err != nil {
+		return err
+	}
+
+	elements := []object.Object{}
+	for _, child := range ast.Children(args[0].(*object.Quote).Node) {
+		elements = append(elements, &object.Quote{Node: child})
+	}
+	return &object.Array{Elements: elements}
+}
+
+func astOperator(args ...object.Object) object.Object {
+	if err := CheckArgs("ast_operator", args, object.QUOTE_OBJ); err != nil {
+		return err
+	}
+
+	switch node := args[0].(*object.Quote).Node.(type) {
+	case *ast.PrefixExpression:
+		return &object.String{Value: node.Operator}
+	case *ast.InfixExpression:
+		return &object.String{Value: node.Operator}
+	default:
+		return newError("argument to `ast_operator` must be PrefixExpression or InfixExpression, got %s", ast.Kind(node))
+	}
+}
+
+func astValue(args ...object.Object) object.Object {
+	if err := CheckArgs("ast_value", args, object.QUOTE_OBJ); err != nil {
+		return err
+	}
+
+	switch node := args[0].(*object.Quote).Node.(type) {
+	case *ast.IntegerLiteral:
+		return &object.Integer{Value: node.Value}
+	case *ast.StringLiteral:
+		return &object.String{Value: node.Value}
+	case *ast.Boolean:
+		return nativeBoolToBooleanObject(node.Value)
+	case *ast.NullLiteral:
+		return NULL
+	case *ast.Identifier:
+		return &object.String{Value: node.Value}
+	default:
+		return newError("argument to `ast_value` must be a literal or identifier, got %s", ast.Kind(node))
+	}
+}
+
+func astIdent(args ...object.Object) object.Object {
+	if err := CheckArgs("ast_ident", args, object.STRING_OBJ); err != nil {
+		return err
+	}
+	name := args[0].(*object.String).Value
+	if !isIdentifierName(name) {
+		return newError("invalid identifier name: %q", name)
+	}
+	return &object.Quote{Node: newIdentifier(name)}
+}
+
+// ast_call(fn, args) fn为quote或者函数名
+func (e *Evaluator) astCall(args ...object.Object) object.Object {
+	if err := CheckArgs("ast_call", args, ANY_OBJ, object.ARRAY_OBJ); err != nil {
+		return err
+	}
+
+	var function ast.Expression
+	switch fn := args[0].(type) {
+	case *object.String:
+		if !isIdentifierName(fn.Value) {
+			return newError("invalid identifier name: %q", fn.Value)
+		}
+		function = newIdentifier(fn.Value)
+	case *object.Quote:
+		exp, ok := fn.Node.(ast.Expression)
+		if !ok {
+			return newError("cannot use %s as an expression", fn.Node.String())
+		}
+		function = exp
+	default:
+		return newError("argument to `ast_call` must be QUOTE or STRING, got %s", fn.Type())
+	}
+
+	arguments, err := e.convertObjectsToExpressions(args[1].(*object.Array).Elements)
+	if err != nil {
+		return err
+	}
+
+	return &object.Quote{Node: &ast.CallExpression{
+		Token:     token.Token{Type: token.LPAREN, Literal: "("},
+		Function:  function,
+		Arguments: arguments,
+	}}
+}
+
+// 与词法分析器一致，标识符由字母、数字和下划线组成，不能以数字开头，并且不能是关键字
+func isIdentifierName(name string) bool {
+	if name == "" || token.LookupIdent(name) != token.IDENT {
+		return false
+	}
+	for i, ch := range name {
+		if !('a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' || i > 0 && '0' <= ch && ch <= '9') {
+			return false
+		}
+	}
+	return true
+}
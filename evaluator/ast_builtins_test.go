@@ -0,0 +1,83 @@
+package evaluator
+
+import (
+	"testing"
+
+	"monkey/object"
+)
+
+func TestASTBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`ast_kind(quote(1 + 2))`, "InfixExpression"},
+		{`ast_kind(quote(f(x)))`, "CallExpression"},
+		{`ast_kind(quote(x))`, "Identifier"},
+		{`len(ast_children(quote(f(1, 2))))`, 3},
+		{`ast_kind(first(ast_children(quote(f(1, 2)))))`, "Identifier"},
+		{`len(ast_children(quote(1)))`, 0},
+		{`ast_operator(quote(1 + 2))`, "+"},
+		{`ast_operator(quote(-a))`, "-"},
+		{`ast_operator(quote(a))`, errorResult("argument to `ast_operator` must be PrefixExpression or InfixExpression, got Identifier")},
+		{`ast_value(quote(5))`, 5},
+		{`ast_value(quote("monkey"))`, "monkey"},
+		{`ast_value(quote(true))`, true},
+		{`ast_value(quote(null))`, nil},
+		{`ast_value(quote(name))`, "name"},
+		{`ast_value(quote(1 + 2))`, errorResult("argument to `ast_value` must be a literal or identifier, got InfixExpression")},
+		{`ast_kind(1)`, errorResult("argument to `ast_kind` must be QUOTE, got INTEGER")},
+		{`ast_value(ast_ident("x1"))`, "x1"},
+		{`ast_ident("1x")`, errorResult(`invalid identifier name: "1x"`)},
+		{`ast_ident("let")`, errorResult(`invalid identifier name: "let"`)},
+		{`ast_call(1, [])`, errorResult("argument to `ast_call` must be QUOTE or STRING, got INTEGER")},
+	}
+
+	testEvalResults(t, tests)
+}
+
+func TestASTConstructors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`ast_ident("total")`, "total"},
+		{`ast_call("add", [1, quote(x + 1)])`, "add(1, (x + 1))"},
+		{`ast_call(ast_ident("f"), [])`, "f()"},
+		{`ast_call(quote(obj.run), ["fast", [1, 2]])`, `(obj.run)(fast, [1, 2])`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		quote, ok := evaluated.(*object.Quote)
+		if !ok {
+			t.Fatalf("expected *object.Quote. got=%T (%+v)", evaluated, evaluated)
+		}
+		if quote.Node.String() != tt.expected {
+			t.Errorf("not equal. want=%q, got=%q", tt.expected, quote.Node.String())
+		}
+	}
+}
+
+func TestDataDrivenMacro(t *testing.T) {
+	// 根据数组中的函数名生成对每个函数的调用
+	input := `
+let callAll = macro(names) {
+	let calls = ast_children(names).map(fn(name) { ast_call(name, [10]) });
+	quote([unquote_splice(calls)]);
+};
+let double = fn(x) { x * 2 };
+let square = fn(x) { x * x };
+callAll([double, square]);
+`
+	evaluated := testExpandAndEval(t, input)
+	array, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+	if len(array.Elements) != 2 {
+		t.Fatalf("wrong number of elements. got=%d", len(array.Elements))
+	}
+	testIntegerObject(t, array.Elements[0], 20)
+	testIntegerObject(t, array.Elements[1], 100)
+}
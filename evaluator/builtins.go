@@ -13,11 +13,13 @@ import (
 // rest:获取数组中除了第一个元素之外的元素组成的数组（新数组）
 // push:在数组最后追加一个元素（新数组）
 // puts:打印
+// type:获取值的类型名，结构体实例为结构体的名称
+// gensym:生成一个唯一的标识符，用于编写卫生的宏
 var builtins = map[string]*object.Builtin{
 	"len": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			if err := CheckArgCount(args, 1); err != nil {
+				return err
 			}
 
 			switch arg := args[0].(type) {
@@ -32,12 +34,8 @@ var builtins = map[string]*object.Builtin{
 	},
 	"first": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
-			}
-
-			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `first` must be ARRAY, got %s", args[0].Type())
+			if err := CheckArgs("first", args, object.ARRAY_OBJ); err != nil {
+				return err
 			}
 
 			arr := args[0].(*object.Array)
@@ -49,12 +47,8 @@ var builtins = map[string]*object.Builtin{
 	},
 	"last": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
-			}
-
-			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `last` must be ARRAY, got %s", args[0].Type())
+			if err := CheckArgs("last", args, object.ARRAY_OBJ); err != nil {
+				return err
 			}
 
 			arr := args[0].(*object.Array)
@@ -67,12 +61,8 @@ var builtins = map[string]*object.Builtin{
 	},
 	"rest": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
-			}
-
-			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `rest` must be ARRAY, got %s", args[0].Type())
+			if err := CheckArgs("rest", args, object.ARRAY_OBJ); err != nil {
+				return err
 			}
 
 			arr := args[0].(*object.Array)
@@ -87,12 +77,8 @@ var builtins = map[string]*object.Builtin{
 	},
 	"push": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 2 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
-			}
-
-			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `push` must be ARRAY, got %s", args[0].Type())
+			if err := CheckArgs("push", args, object.ARRAY_OBJ, ANY_OBJ); err != nil {
+				return err
 			}
 
 			arr := args[0].(*object.Array)
@@ -104,6 +90,19 @@ var builtins = map[string]*object.Builtin{
 			return &object.Array{Elements: newElements}
 		},
 	},
+	"type": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if err := CheckArgCount(args, 1); err != nil {
+				return err
+			}
+
+			if instance, ok := args[0].(*object.Instance); ok {
+				return &object.String{Value: instance.Struct.Name}
+			}
+			return &object.String{Value: string(args[0].Type())}
+		},
+	},
+	"gensym": &object.Builtin{Fn: gensym},
 	"puts": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			for _, arg := range args {
@@ -113,3 +112,33 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 }
+
+// ANY_OBJ 在CheckArgs中表示该位置的参数可以是任意类型
+const ANY_OBJ object.ObjectType = "ANY"
+
+// NewError 创建一个错误对象，供宿主程序注册的内置函数返回错误
+func NewError(format string, a ...interface{}) *object.Error {
+	return newError(format, a...)
+}
+
+// CheckArgCount 检查参数数量
+func CheckArgCount(args []object.Object, want int) *object.Error {
+	if len(args) != want {
+		return newError("wrong number of arguments. got=%d, want=%d", len(args), want)
+	}
+	return nil
+}
+
+// CheckArgs 检查参数数量以及每个参数的类型，name用于错误提示
+func CheckArgs(name string, args []object.Object, types ...object.ObjectType) *object.Error {
+	if err := CheckArgCount(args, len(types)); err != nil {
+		return err
+	}
+
+	for i, t := range types {
+		if t != ANY_OBJ && args[i].Type() != t {
+			return newError("argument to `%s` must be %s, got %s", name, t, args[i].Type())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package evaluator
+
+import (
+	"fmt"
+
+	"monkey/object"
+	"monkey/token"
+)
+
+// RedeclareMode 在同一作用域中重复声明let时的处理方式
+// 重新声明常量总是错误
+type RedeclareMode int
+
+const (
+	RedeclareAllow RedeclareMode = iota // 允许，覆盖原来的值
+	RedeclareWarn                       // 允许，但记录一条警告
+	RedeclareError                      // 返回错误
+)
+
+// SetRedeclareMode 设置重复声明的处理方式，默认为RedeclareAllow
+func (e *Evaluator) SetRedeclareMode(mode RedeclareMode) {
+	e.redeclare = mode
+}
+
+// Warnings 返回上次Reset以来产生的警告
+func (e *Evaluator) Warnings() []string {
+	return e.warnings
+}
+
+// 将let、const或struct语句产生的绑定写入当前作用域，全部检查通过后才写入
+// pos为声明语句的位置，用于警告信息
+func (e *Evaluator) declare(pos token.Token, constant bool, bindings map[string]object.Object, env *object.Environment) *object.Error {
+	for name := range bindings {
+		if env.IsConst(name) {
+			return newError("cannot reassign constant `%s`", name)
+		}
+		if !env.Declared(name) {
+			continue
+		}
+
+		switch e.redeclare {
+		case RedeclareWarn:
+			e.warnings = append(e.warnings, fmt.Sprintf("line %d, column %d: `%s` redeclared in the same scope",
+				pos.Line, pos.Column, name))
+		case RedeclareError:
+			return newError("`%s` already declared in this scope", name)
+		}
+	}
+
+	for name, val := range bindings {
+		if constant {
+			env.SetConst(name, val)
+		} else {
+			env.Set(name, val)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package evaluator
+
+import (
+	"testing"
+
+	"monkey/object"
+)
+
+func TestConstStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"const x = 5; x;", 5},
+		{"const [a, b] = [1, 2]; a + b;", 3},
+		{"const x = 5; let x = 6;", "cannot reassign constant `x`"},
+		{"const x = 5; const x = 6;", "cannot reassign constant `x`"},
+		{"const [a, b] = [1, 2]; let {b} = {\"b\": 3};", "cannot reassign constant `b`"},
+		// 内层作用域可以遮蔽外层的常量
+		{"const x = 5; let f = fn() { let x = 6; x }; f() + x;", 11},
+		{"const x = 5; let f = fn(x) { x }; f(7);", 7},
+		{"let x = 5; const x = 6; x;", 6},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestRedeclareMode(t *testing.T) {
+	input := "let x = 1; let f = fn() { let x = 2; x }; let x = f(); x;"
+
+	e := New()
+	testIntegerObject(t, e.Eval(testParseProgram(input), object.NewEnvironment()), 2)
+	if len(e.Warnings()) != 0 {
+		t.Errorf("unexpected warnings. got=%q", e.Warnings())
+	}
+
+	e = New()
+	e.SetRedeclareMode(RedeclareWarn)
+	testIntegerObject(t, e.Eval(testParseProgram(input), object.NewEnvironment()), 2)
+	warnings := e.Warnings()
+	if len(warnings) != 1 || warnings[0] != "line 1, column 43: `x` redeclared in the same scope" {
+		t.Errorf("wrong warnings. got=%q", warnings)
+	}
+
+	e = New()
+	e.SetRedeclareMode(RedeclareError)
+	evaluated := e.Eval(testParseProgram(input), object.NewEnvironment())
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "`x` already declared in this scope" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestRedeclareStruct(t *testing.T) {
+	input := "let Point = 1;\nstruct Point { x, y };"
+
+	e := New()
+	e.SetRedeclareMode(RedeclareWarn)
+	e.Eval(testParseProgram(input), object.NewEnvironment())
+	warnings := e.Warnings()
+	if len(warnings) != 1 || warnings[0] != "line 2, column 1: `Point` redeclared in the same scope" {
+		t.Errorf("wrong warnings. got=%q", warnings)
+	}
+
+	e = New()
+	e.SetRedeclareMode(RedeclareError)
+	evaluated := e.Eval(testParseProgram(input), object.NewEnvironment())
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "`Point` already declared in this scope" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
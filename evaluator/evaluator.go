@@ -1,6 +1,7 @@
 package evaluator
 
 import (
+	"context"
 	"fmt"
 
 	"monkey/ast"
@@ -9,106 +10,213 @@ import (
 
 var (
 	// null只有一种
-	NULL = &object.Null{}
+	NULL = object.NULL
 	// bool只有两种可能，所以不必每次都创建实例。使用以下两个引用代替每次新实例
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	TRUE  = object.TRUE
+	FALSE = object.FALSE
 )
 
+// Evaluator 求值器，保存一次解释过程中的状态
+// 内置函数按求值器注册，不同的嵌入实例之间互不影响
+type Evaluator struct {
+	builtins map[string]*object.Builtin
+	ctx      context.Context // 用于从外部取消求值
+
+	limits    Limits
+	steps     int // 已经求值的节点数
+	depth     int // 当前函数调用深度
+	allocated int // 累计分配的近似字节数
+
+	stack []object.StackFrame // 当前的调用栈
+
+	redeclare RedeclareMode
+	warnings  []string
+
+	macroEnv *object.Environment   // 宏所在的环境，最近一次ExpandMacros时记录
+	origins  map[ast.Node]ast.Node // 宏展开期间unquote插入的拷贝节点 -> 原节点
+
+	loader  ModuleLoader
+	modules map[string]*module // 已经加载的模块，加载中的为nil
+}
+
+func New() *Evaluator {
+	e := &Evaluator{builtins: make(map[string]*object.Builtin)}
+	// 依赖求值器状态的内置函数
+	e.builtins["macroexpand"] = &object.Builtin{Fn: e.macroexpand}
+	e.builtins["macroexpand1"] = &object.Builtin{Fn: e.macroexpand1}
+	e.builtins["import"] = &object.Builtin{Fn: e.importModule}
+	for name, builtin := range e.astBuiltins() {
+		e.builtins[name] = builtin
+	}
+	return e
+}
+
+// RegisterBuiltin 注册宿主程序提供的内置函数，同名时覆盖默认的内置函数
+func (e *Evaluator) RegisterBuiltin(name string, fn object.BuiltinFunction) {
+	e.builtins[name] = &object.Builtin{Fn: fn}
+}
+
+// SetContext 设置用于取消求值的context，在语句之间和函数调用时检查
+func (e *Evaluator) SetContext(ctx context.Context) {
+	e.ctx = ctx
+}
+
+func (e *Evaluator) checkContext() *object.Error {
+	if e.ctx == nil {
+		return nil
+	}
+	if err := e.ctx.Err(); err != nil {
+		return &object.Error{Message: "evaluation cancelled: " + err.Error(), Kind: object.CANCEL_ERROR}
+	}
+	return nil
+}
+
+// 先查找当前求值器注册的内置函数，再查找默认的内置函数
+func (e *Evaluator) lookupBuiltin(name string) (*object.Builtin, bool) {
+	if builtin, ok := e.builtins[name]; ok {
+		return builtin, true
+	}
+	builtin, ok := builtins[name]
+	return builtin, ok
+}
+
+// Eval 使用一个新的求值器求值
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	return New().Eval(node, env)
+}
+
+func (e *Evaluator) Eval(node ast.Node, env *object.Environment) object.Object {
+	if err := e.step(); err != nil {
+		return err
+	}
+
 	switch nodeT := node.(type) {
 	case *ast.Program:
-		return evalProgram(nodeT, env)
+		return e.evalProgram(nodeT, env)
 	case *ast.ExpressionStatement:
-		return Eval(nodeT.Expression, env)
+		return e.Eval(nodeT.Expression, env)
 	case *ast.IntegerLiteral:
-		return &object.Integer{Value: nodeT.Value}
+		return e.track(&object.Integer{Value: nodeT.Value})
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(nodeT.Value)
+	case *ast.NullLiteral:
+		return NULL
 	case *ast.PrefixExpression:
-		right := Eval(nodeT.Right, env)
+		right := e.Eval(nodeT.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(nodeT.Operator, right)
+		return e.track(evalPrefixExpression(nodeT.Operator, right))
 	case *ast.InfixExpression:
-		left := Eval(nodeT.Left, env)
+		left := e.Eval(nodeT.Left, env)
 		if isError(left) {
 			return left
 		}
-		right := Eval(nodeT.Right, env)
+		right := e.Eval(nodeT.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalInfixExpression(nodeT.Operator, left, right)
+		return e.track(evalInfixExpression(nodeT.Operator, left, right))
 	case *ast.BlockStatement:
-		return evalBlockStatement(nodeT, env)
+		return e.evalBlockStatement(nodeT, env)
 	case *ast.IfExpression:
-		return evalIfExpression(nodeT, env)
+		return e.evalIfExpression(nodeT, env)
 	case *ast.ReturnStatement:
-		val := Eval(nodeT.ReturnValue, env)
+		val := e.Eval(nodeT.ReturnValue, env)
 		if isError(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
 	case *ast.LetStatement:
-		val := Eval(nodeT.Value, env)
+		val := e.Eval(nodeT.Value, env)
 		if isError(val) {
 			return val
 		}
+		if nodeT.Pattern != nil {
+			return e.destructure(nodeT, val, env)
+		}
+		// 为匿名函数记录名称，用于调用栈
+		if fn, ok := val.(*object.Function); ok && fn.Name == "" {
+			fn.Name = nodeT.Name.Value
+		}
 		// 哈希映射
-		env.Set(nodeT.Name.Value, val)
+		if err := e.declare(nodeT.Token, nodeT.Constant(), map[string]object.Object{nodeT.Name.Value: val}, env); err != nil {
+			return err
+		}
 	case *ast.Identifier:
-		return evalIdentifier(nodeT, env)
+		return e.evalIdentifier(nodeT, env)
 	case *ast.FunctionLiteral:
-		params := nodeT.Parameters
-		body := nodeT.Body
-		return &object.Function{Parameters: params, Body: body, Env: env}
+		return e.track(&object.Function{
+			Parameters: nodeT.Parameters,
+			Defaults:   nodeT.Defaults,
+			Rest:       nodeT.Rest,
+			Body:       nodeT.Body,
+			Env:        env,
+		})
+	case *ast.MacroLiteral:
+		return newError("macro literal must be bound with `let`")
 	case *ast.CallExpression:
 		// quote不对参数求值,quote只能使用一个参数
 		if nodeT.Function.TokenLiteral() == "quote" {
-			return quote(nodeT.Arguments[0], env)
+			return e.quote(nodeT, env)
 		}
 
-		function := Eval(nodeT.Function, env)
+		function := e.evalCallee(nodeT.Function, env)
 		if isError(function) {
 			return function
 		}
-		args := evalExpressions(nodeT.Arguments, env)
+		args := e.evalExpressions(nodeT.Arguments, env)
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
-		return applyFunction(function, args)
+		named, err := e.evalNamedArguments(nodeT.NamedArguments, env)
+		if err != nil {
+			return err
+		}
+		return e.callFunction(nodeT, function, args, named)
 	case *ast.StringLiteral:
-		return &object.String{Value: nodeT.Value}
+		return e.track(&object.String{Value: nodeT.Value})
 	case *ast.ArrayLiteral:
-		elements := evalExpressions(nodeT.Elements, env)
+		elements := e.evalExpressions(nodeT.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
 			return elements[0]
 		}
-		return &object.Array{Elements: elements}
+		return e.track(&object.Array{Elements: elements})
+	case *ast.DotExpression:
+		return e.evalDotExpression(nodeT, env)
 	case *ast.IndexExpression:
-		left := Eval(nodeT.Left, env)
+		left := e.Eval(nodeT.Left, env)
 		if isError(left) {
 			return left
 		}
 
-		index := Eval(nodeT.Index, env)
+		index := e.Eval(nodeT.Index, env)
 		if isError(index) {
 			return index
 		}
 		return evalIndexExpression(left, index)
 	case *ast.HashLiteral:
-		return evalHashLiteral(nodeT, env)
+		return e.evalHashLiteral(nodeT, env)
+	case *ast.MatchExpression:
+		return e.evalMatchExpression(nodeT, env)
+	case *ast.StructStatement:
+		return e.evalStructStatement(nodeT, env)
+	case *ast.ThrowStatement:
+		return e.evalThrowStatement(nodeT, env)
+	case *ast.TryExpression:
+		return e.evalTryExpression(nodeT, env)
 	}
 	return nil
 }
 
-func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+func (e *Evaluator) evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range program.Statements {
-		result = Eval(statement, env)
+		if err := e.checkContext(); err != nil {
+			return err
+		}
+		result = e.Eval(statement, env)
 
 		switch rt := result.(type) {
 		case *object.ReturnValue:
@@ -158,6 +266,8 @@ func evalInfixExpression(operator string, left object.Object, right object.Objec
 		return evalIntegerInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
+	case left.Type() == object.INSTANCE_OBJ && right.Type() == object.INSTANCE_OBJ:
+		return evalInstanceInfixExpression(operator, left, right)
 	case operator == "==":
 		return nativeBoolToBooleanObject(left == right)
 	case operator == "!=":
@@ -213,15 +323,15 @@ func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	return FALSE
 }
 
-func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(ie.Condition, env)
+func (e *Evaluator) evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
+	condition := e.Eval(ie.Condition, env)
 	if isError(condition) {
 		return condition
 	}
 	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+		return e.Eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
+		return e.Eval(ie.Alternative, env)
 	} else {
 		return NULL
 	}
@@ -240,11 +350,14 @@ func isTruthy(obj object.Object) bool {
 	}
 }
 
-func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+func (e *Evaluator) evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range block.Statements {
-		result = Eval(statement, env)
+		if err := e.checkContext(); err != nil {
+			return err
+		}
+		result = e.Eval(statement, env)
 
 		if result != nil {
 			rt := result.Type()
@@ -267,22 +380,22 @@ func isError(obj object.Object) bool {
 	return false
 }
 
-func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+func (e *Evaluator) evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
 	if val, ok := env.Get(node.Value); ok {
 		return val
 	}
 	// 如果当前环境没有发现对应的标识符，则在内置函数环境中查找
-	if builtin, ok := builtins[node.Value]; ok {
+	if builtin, ok := e.lookupBuiltin(node.Value); ok {
 		return builtin
 	}
 
 	return newError("identifier not found: " + node.Value)
 }
 
-func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
+func (e *Evaluator) evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
 	var result []object.Object
-	for _, e := range exps {
-		evaluated := Eval(e, env)
+	for _, exp := range exps {
+		evaluated := e.Eval(exp, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
 		}
@@ -291,29 +404,226 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 	return result
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+// 调用函数并维护调用栈，错误第一次离开函数调用时记录当时的调用栈
+func (e *Evaluator) callFunction(node *ast.CallExpression, fn object.Object, args []object.Object, named map[string]object.Object) object.Object {
+	return e.callWithFrame(e.newStackFrame(node, fn), fn, args, named)
+}
+
+// 方法(例如arr.map(f))调用作为参数传入的函数，调用位置为方法调用所在的位置
+func (e *Evaluator) callArgument(fn object.Object, args ...object.Object) object.Object {
+	frame := object.StackFrame{Function: functionName(nil, fn)}
+	if n := len(e.stack); n > 0 {
+		frame.Line, frame.Column = e.stack[n-1].Line, e.stack[n-1].Column
+	}
+	return e.callWithFrame(frame, fn, args, nil)
+}
+
+func (e *Evaluator) callWithFrame(frame object.StackFrame, fn object.Object, args []object.Object, named map[string]object.Object) object.Object {
+	e.stack = append(e.stack, frame)
+	defer func() { e.stack = e.stack[:len(e.stack)-1] }()
+
+	result := e.applyFunction(fn, args, named)
+	if errObj, ok := result.(*object.Error); ok && errObj.Stack == nil {
+		errObj.Stack = append([]object.StackFrame{}, e.stack...)
+	}
+	return result
+}
+
+func (e *Evaluator) newStackFrame(node *ast.CallExpression, fn object.Object) object.StackFrame {
+	pos := node.Token
+	switch callee := node.Function.(type) {
+	case *ast.Identifier:
+		pos = callee.Token
+	case *ast.DotExpression:
+		pos = callee.Name.Token
+	}
+
+	return object.StackFrame{
+		Function: functionName(node.Function, fn),
+		Line:     pos.Line,
+		Column:   pos.Column,
+	}
+}
+
+func functionName(callee ast.Expression, fn object.Object) string {
+	switch calleeT := callee.(type) {
+	case *ast.Identifier:
+		return calleeT.Value
+	case *ast.DotExpression:
+		return calleeT.Name.Value
+	}
+	if function, ok := fn.(*object.Function); ok && function.Name != "" {
+		return function.Name
+	}
+	return "<anonymous>"
+}
+
+// ApplyFunction 供宿主程序调用Monkey函数或内置函数
+func ApplyFunction(fn object.Object, args []object.Object) object.Object {
+	return New().ApplyFunction(fn, args)
+}
+
+func (e *Evaluator) ApplyFunction(fn object.Object, args []object.Object) object.Object {
+	return e.applyFunction(fn, args, nil)
+}
+
+// named为命名参数，内置函数不支持命名参数
+func (e *Evaluator) applyFunction(fn object.Object, args []object.Object, named map[string]object.Object) object.Object {
+	if err := e.checkContext(); err != nil {
+		return err
+	}
 
 	switch fnT := fn.(type) {
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fnT, args)
-		evaluated := Eval(fnT.Body, extendedEnv)
-		return unWarpReturnValue(evaluated)
+		if err := e.enterCall(); err != nil {
+			return err
+		}
+		defer e.leaveCall()
+
+		// 蹦床，循环执行尾调用
+		for {
+			extendedEnv, err := e.extendFunctionEnv(fnT, args, named)
+			if err != nil {
+				return err
+			}
+			evaluated := unWarpReturnValue(e.evalTailBlock(fnT.Body, extendedEnv, true))
+
+			tc, ok := evaluated.(*tailCall)
+			if !ok {
+				return evaluated
+			}
+			if err := e.checkContext(); err != nil {
+				return err
+			}
+			// 尾调用替换当前函数的帧，记录被省略的帧数，调用栈中仍能看出发生过尾调用
+			if n := len(e.stack); n > 0 {
+				tc.frame.Elided = e.stack[n-1].Elided + 1
+				e.stack[n-1] = tc.frame
+			}
+			fnT, args, named = tc.fn.(*object.Function), tc.args, tc.named
+		}
 	case *object.Builtin:
-		return fnT.Fn(args...)
+		if len(named) > 0 {
+			return newError("builtin function does not accept named arguments")
+		}
+		return e.track(fnT.Fn(args...))
+	case *object.StructType:
+		return e.track(newInstance(fnT, args, named))
 	default:
 		return newError("not a function: %s", fn.Type())
 	}
 }
 
 // 扩展的是定义函数时的环境，而不是当前环境。闭包得以实现
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+// 依次绑定位置参数、剩余参数、命名参数，最后对未绑定参数的默认值求值
+func (e *Evaluator) extendFunctionEnv(fn *object.Function, args []object.Object, named map[string]object.Object) (*object.Environment, object.Object) {
+	if len(args) > len(fn.Parameters) && fn.Rest == nil {
+		return nil, arityError(fn, len(args))
+	}
+
 	env := object.NewEnclosedEnvironment(fn.Env)
+	bound := make(map[string]bool, len(fn.Parameters))
 
 	for paramIdx, param := range fn.Parameters {
+		if paramIdx >= len(args) {
+			break
+		}
 		// 为什么不是env.Set(param.Value,param)呢
 		env.Set(param.Value, args[paramIdx])
+		bound[param.Value] = true
+	}
+
+	if fn.Rest != nil {
+		rest := []object.Object{}
+		if len(args) > len(fn.Parameters) {
+			rest = append(rest, args[len(fn.Parameters):]...)
+		}
+		env.Set(fn.Rest.Value, &object.Array{Elements: rest})
+	}
+
+	for name, val := range named {
+		if !isParameter(fn, name) {
+			return nil, newError("unexpected named argument `%s` to `%s`", name, displayName(fn))
+		}
+		if bound[name] {
+			return nil, newError("multiple values for argument `%s` to `%s`", name, displayName(fn))
+		}
+		env.Set(name, val)
+		bound[name] = true
+	}
+
+	for _, param := range fn.Parameters {
+		if bound[param.Value] {
+			continue
+		}
+		def, ok := fn.Defaults[param.Value]
+		if !ok {
+			if len(named) == 0 {
+				return nil, arityError(fn, len(args))
+			}
+			return nil, newError("missing argument `%s` to `%s`", param.Value, displayName(fn))
+		}
+		// 默认值可以引用之前的参数
+		val := e.Eval(def, env)
+		if isError(val) {
+			return nil, val
+		}
+		env.Set(param.Value, val)
 	}
-	return env
+	return env, nil
+}
+
+func isParameter(fn *object.Function, name string) bool {
+	for _, param := range fn.Parameters {
+		if param.Value == name {
+			return true
+		}
+	}
+	return false
+}
+
+// 参数数量不符，want为参数数量的范围
+func arityError(fn *object.Function, got int) *object.Error {
+	required := len(fn.Parameters) - len(fn.Defaults)
+
+	var want string
+	switch {
+	case fn.Rest != nil:
+		want = fmt.Sprintf("at least %d", required)
+	case required == len(fn.Parameters):
+		want = fmt.Sprintf("%d", required)
+	default:
+		want = fmt.Sprintf("%d..%d", required, len(fn.Parameters))
+	}
+
+	return newError("wrong number of arguments to `%s`: want=%s, got=%d", displayName(fn), want, got)
+}
+
+// 按源代码顺序对命名参数求值
+func (e *Evaluator) evalNamedArguments(args []*ast.NamedArgument, env *object.Environment) (map[string]object.Object, object.Object) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	named := make(map[string]object.Object, len(args))
+	for _, arg := range args {
+		if _, ok := named[arg.Name.Value]; ok {
+			return nil, newError("duplicate named argument `%s`", arg.Name.Value)
+		}
+		val := e.Eval(arg.Value, env)
+		if isError(val) {
+			return nil, val
+		}
+		named[arg.Name.Value] = val
+	}
+	return named, nil
+}
+
+func displayName(fn *object.Function) string {
+	if fn.Name != "" {
+		return fn.Name
+	}
+	return "<anonymous>"
 }
 
 // 解包是为了避免return向上冒泡，使外层函数停止取值
@@ -360,11 +670,11 @@ func evalHashIndexExpression(hash, index object.Object) object.Object {
 	return pair.Value
 }
 
-func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+func (e *Evaluator) evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
 	pairs := make(map[object.HashKey]object.HashPair)
 
-	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
+	for _, pair := range node.Pairs {
+		key := e.Eval(pair.Key, env)
 		if isError(key) {
 			return key
 		}
@@ -375,7 +685,7 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 			return newError("unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
+		value := e.Eval(pair.Value, env)
 		if isError(value) {
 			return value
 		}
@@ -383,22 +693,11 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 		hashed := hashKey.HashKey()
 		pairs[hashed] = object.HashPair{Key: key, Value: value}
 	}
-	return &object.Hash{Pairs: pairs}
+	return e.track(&object.Hash{Pairs: pairs})
 }
 
-func DefineMacros(program *ast.Program, env *object.Environment) {
-	definitions := []int{}
-
-	// 查找宏定义并删除，记录在statements中的位置
-	for i, statement := range program.Statements {
-		if isMacroDefinition(statement) {
-			addMacro(statement, env)
-			definitions = append(definitions, i)
-		}
-	}
-
-	for i := len(definitions) - 1; i >= 0; i-- {
-		definitionIndex := definitions[i]
-		program.Statements = append(program.Statements[:definitionIndex], program.Statements[definitionIndex+1:]...)
-	}
+// DefineMacros 将程序顶层的宏定义放入env，返回去掉宏定义之后的程序，传入的程序不会被修改
+// 代码块中的宏定义在ExpandMacros时处理
+func DefineMacros(program *ast.Program, env *object.Environment) *ast.Program {
+	return &ast.Program{Statements: defineMacros(program.Statements, env)}
 }
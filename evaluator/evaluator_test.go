@@ -1,7 +1,9 @@
 package evaluator
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"monkey/lexer"
 	"monkey/object"
@@ -222,6 +224,14 @@ func TestErrorHandling(t *testing.T) {
 			`999[1]`,
 			"index operator not supported: INTEGER",
 		},
+		{
+			"let add = fn(a, b) { a + b }; add(1);",
+			"wrong number of arguments to `add`: want=2, got=1",
+		},
+		{
+			"fn(x) { x }(1, 2)",
+			"wrong number of arguments to `<anonymous>`: want=1, got=2",
+		},
 	}
 
 	for _, tt := range tests {
@@ -300,6 +310,47 @@ func TestFunctionApplication(t *testing.T) {
 	}
 }
 
+func TestFunctionParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let f = fn(a, b = 10) { a + b }; f(1);", 11},
+		{"let f = fn(a, b = 10) { a + b }; f(1, 2);", 3},
+		{"let f = fn(a, b = a * 2) { a + b }; f(3);", 9},
+		{"let f = fn(a, ...rest) { len(rest) }; f(1, 2, 3);", 2},
+		{"let f = fn(a, ...rest) { len(rest) }; f(1);", 0},
+		{"let f = fn(...rest) { rest[1] }; f(1, 2, 3);", 2},
+		{"let f = fn(a, b) { a - b }; f(b: 1, a: 3);", 2},
+		{"let f = fn(a, b = 2, c = 3) { a * 100 + b * 10 + c }; f(1, c: 5);", 125},
+		{"let f = fn(a, b = 10) { a + b }; f();", "wrong number of arguments to `f`: want=1..2, got=0"},
+		{"let f = fn(a, ...rest) { a }; f();", "wrong number of arguments to `f`: want=at least 1, got=0"},
+		{"let f = fn(a, b) { a }; f(1, c: 2);", "unexpected named argument `c` to `f`"},
+		{"let f = fn(a, b) { a }; f(1, a: 2);", "multiple values for argument `a` to `f`"},
+		{"let f = fn(a, b) { a }; f(b: 2);", "missing argument `a` to `f`"},
+		{"let f = fn(a, b) { a }; f(a: 1, a: 2);", "duplicate named argument `a`"},
+		{`len(s: "abc")`, "builtin function does not accept named arguments"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
 func TestEnclosingEnvironments(t *testing.T) {
 	input := `
 let first = 10;
@@ -629,3 +680,126 @@ func testNullObject(t *testing.T, obj object.Object) bool {
 	}
 	return true
 }
+
+func TestRegisterBuiltin(t *testing.T) {
+	e := New()
+	e.RegisterBuiltin("double", func(args ...object.Object) object.Object {
+		if err := CheckArgs("double", args, object.INTEGER_OBJ); err != nil {
+			return err
+		}
+		return &object.Integer{Value: args[0].(*object.Integer).Value * 2}
+	})
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`double(21)`, 42},
+		{`double("a")`, "argument to `double` must be INTEGER, got STRING"},
+		{`double(1, 2)`, "wrong number of arguments. got=2, want=1"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		evaluated := e.Eval(program, object.NewEnvironment())
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+
+	// 注册的内置函数只对该求值器可见
+	errObj, ok := testEval(`double(1)`).(*object.Error)
+	if !ok || errObj.Message != "identifier not found: double" {
+		t.Errorf("builtin leaked into another evaluator. got=%+v", errObj)
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	fib := `let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(40);`
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	timeout, cancelTimeout := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelTimeout()
+
+	tests := []struct {
+		ctx             context.Context
+		expectedMessage string
+	}{
+		{cancelled, "evaluation cancelled: context canceled"},
+		{timeout, "evaluation cancelled: context deadline exceeded"},
+	}
+
+	for _, tt := range tests {
+		e := New()
+		e.SetContext(tt.ctx)
+		program := parser.New(lexer.New(fib)).ParseProgram()
+		evaluated := e.Eval(program, object.NewEnvironment())
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Kind != object.CANCEL_ERROR {
+			t.Errorf("wrong error kind. got=%q", errObj.Kind)
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestStackTrace(t *testing.T) {
+	input := `let inner = fn(x) {
+  x + y
+};
+let outer = fn(x) {
+  inner(x) + 1
+};
+outer(1);`
+
+	evaluated := testEval(input)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+
+	expected := []object.StackFrame{
+		{Function: "outer", Line: 7, Column: 1},
+		{Function: "inner", Line: 5, Column: 3},
+	}
+	if len(errObj.Stack) != len(expected) {
+		t.Fatalf("wrong stack length. want=%d, got=%d (%+v)", len(expected), len(errObj.Stack), errObj.Stack)
+	}
+	for i, frame := range expected {
+		if errObj.Stack[i] != frame {
+			t.Errorf("stack[%d] wrong. want=%+v, got=%+v", i, frame, errObj.Stack[i])
+		}
+	}
+
+	expectedTraceback := `Traceback (most recent call last):
+  line 7, column 1, in call to outer
+  line 5, column 3, in call to inner
+ERROR: identifier not found: y`
+	if errObj.Traceback() != expectedTraceback {
+		t.Errorf("wrong traceback. want=%q, got=%q", expectedTraceback, errObj.Traceback())
+	}
+
+	// 顶层的错误没有调用栈
+	errObj, ok = testEval("5 + true").(*object.Error)
+	if !ok || errObj.Stack != nil || errObj.Traceback() != errObj.Inspect() {
+		t.Errorf("top level error should not have a stack. got=%+v", errObj)
+	}
+}
@@ -0,0 +1,94 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+)
+
+/*
+异常处理
+throw把任意值包装成错误对象，try可以捕获运行时错误，包括内置函数的错误
+超出执行限制和被取消的错误不能被捕获，也不会执行finally
+*/
+
+func (e *Evaluator) evalThrowStatement(ts *ast.ThrowStatement, env *object.Environment) object.Object {
+	val := e.Eval(ts.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	message := val.Inspect()
+	if str, ok := val.(*object.String); ok {
+		message = str.Value
+	}
+
+	return &object.Error{
+		Message: message,
+		Value:   val,
+		Stack:   append([]object.StackFrame{}, e.stack...),
+	}
+}
+
+func (e *Evaluator) evalTryExpression(te *ast.TryExpression, env *object.Environment) object.Object {
+	result := e.Eval(te.Block, env)
+
+	if errObj, ok := result.(*object.Error); ok {
+		if errObj.Kind != object.RUNTIME_ERROR {
+			return errObj
+		}
+		if te.Catch != nil {
+			catchEnv := object.NewEnclosedEnvironment(env)
+			catchEnv.Set(te.CatchParam.Value, e.errorToHash(errObj))
+			result = e.Eval(te.Catch, catchEnv)
+		}
+	}
+
+	if te.Finally != nil {
+		// finally中的错误和return覆盖之前的结果
+		finally := e.Eval(te.Finally, env)
+		if finally != nil {
+			ft := finally.Type()
+			if ft == object.RETURN_VALUE_OBJ || ft == object.ERROR_OBJ {
+				return finally
+			}
+		}
+	}
+
+	if result == nil {
+		return NULL
+	}
+	return result
+}
+
+// 捕获的错误以哈希表示 {"message": ..., "stack": [...], "value": ...}
+// stack中每一帧为 {"function": ..., "line": ..., "column": ...}
+func (e *Evaluator) errorToHash(errObj *object.Error) object.Object {
+	frames := make([]object.Object, 0, len(errObj.Stack))
+	for _, frame := range errObj.Stack {
+		frames = append(frames, newHash(map[string]object.Object{
+			"function": &object.String{Value: frame.Function},
+			"line":     &object.Integer{Value: int64(frame.Line)},
+			"column":   &object.Integer{Value: int64(frame.Column)},
+		}))
+	}
+
+	var value object.Object = NULL
+	if errObj.Value != nil {
+		value = errObj.Value
+	}
+
+	return e.track(newHash(map[string]object.Object{
+		"message": &object.String{Value: errObj.Message},
+		"stack":   &object.Array{Elements: frames},
+		"value":   value,
+	}))
+}
+
+func newHash(fields map[string]object.Object) *object.Hash {
+	pairs := make(map[object.HashKey]object.HashPair, len(fields))
+	for name, val := range fields {
+		key := &object.String{Value: name}
+		pairs[key.HashKey()] = object.HashPair{Key: key, Value: val}
+	}
+	return &object.Hash{Pairs: pairs}
+}
@@ -0,0 +1,80 @@
+package evaluator
+
+import (
+	"testing"
+
+	"monkey/object"
+)
+
+func TestTryCatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`try { 1 } catch (e) { 2 }`, 1},
+		{`try { throw "oops"; 1 } catch (e) { 2 }`, 2},
+		{`try { throw "oops" } catch (e) { e["message"] }`, "oops"},
+		{`try { throw 42 } catch (e) { e["value"] }`, 42},
+		{`try { len(1, 2) } catch (e) { e["message"] }`, "wrong number of arguments. got=2, want=1"},
+		{`try { 1 + "a" } catch (e) { e["value"] }`, nil},
+		{`let f = fn() { throw "inner" }; try { f() } catch (e) { len(e["stack"]) }`, 1},
+		{`let f = fn() { throw "inner" }; try { f() } catch (e) { e["stack"][0]["function"] }`, "f"},
+		{`let f = fn() { try { return 1; } finally { 2 } }; f();`, 1},
+		{`let f = fn() { try { return 1; } finally { return 2; } }; f();`, 2},
+		{`let x = try { throw 1 } catch (e) { 5 } finally { 6 }; x;`, 5},
+		{`try { throw 1 } catch (e) { try { throw 2 } catch (inner) { e["value"] + inner["value"] } }`, 3},
+		{`try { throw 1 } catch (e) { throw "again" }`, errorResult("again")},
+		{`try { throw "escaped" } finally { 1 }`, errorResult("escaped")},
+		{`try { 1 } finally { throw "from finally" }`, errorResult("from finally")},
+		{`throw [1, 2];`, errorResult("[1, 2]")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. want=%q, got=%q", expected, str.Value)
+			}
+		case errorResult:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+// 区分期望的错误信息和期望的字符串结果
+type errorResult string
+
+func TestLimitErrorsAreNotCaught(t *testing.T) {
+	e := New()
+	e.SetLimits(Limits{MaxSteps: 50})
+
+	input := `let loop = fn() { 1 + loop() };
+try { loop() } catch (err) { 1 } finally { 2 };`
+	evaluated := e.Eval(testParseProgram(input), object.NewEnvironment())
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Kind != object.LIMIT_ERROR {
+		t.Errorf("wrong error kind. want=%q, got=%q", object.LIMIT_ERROR, errObj.Kind)
+	}
+}
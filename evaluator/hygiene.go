@@ -0,0 +1,356 @@
+package evaluator
+
+import (
+	"sync/atomic"
+
+	"monkey/ast"
+	"monkey/object"
+	"monkey/token"
+)
+
+/*
+宏的卫生
+宏展开时，由宏本身的quote引入的绑定(let、函数参数、catch参数、模式中的变量)会被重命名为新的名称，
+按作用域查找宏体内引用的是哪个绑定，只有引用宏引入的绑定的标识符一起重命名，
+外层变量、全局变量和内置函数的引用保持原样，因此不会捕获或遮蔽调用处的同名变量。
+宏引入的函数的参数被重命名时，宏体内对该函数的命名参数调用一起重命名。
+通过unquote插入的调用处的代码保持原样。
+新名称形如 tmp__b，只由字母和下划线组成，因此展开后的代码打印出来仍能被重新解析，
+用户代码应避免使用带双下划线的名称
+*/
+
+var gensymCounter int64
+
+// 生成一个唯一的名称，如 tmp__a，计数器用字母表示
+func freshName(prefix string) string {
+	return prefix + "__" + letterCount(atomic.AddInt64(&gensymCounter, 1))
+}
+
+// 1 -> a，26 -> z，27 -> aa
+func letterCount(n int64) string {
+	var letters []byte
+	for ; n > 0; n = (n - 1) / 26 {
+		letters = append([]byte{byte('a' + (n-1)%26)}, letters...)
+	}
+	return string(letters)
+}
+
+func newIdentifier(name string) *ast.Identifier {
+	return &ast.Identifier{Token: token.Token{Type: token.IDENT, Literal: name}, Value: name}
+}
+
+// gensym([prefix]) 返回一个引用了新标识符的quote，在宏中通过unquote使用
+func gensym(args ...object.Object) object.Object {
+	prefix := "g"
+	if len(args) > 0 {
+		if err := CheckArgs("gensym", args, object.STRING_OBJ); err != nil {
+			return err
+		}
+		prefix = args[0].(*object.String).Value
+	}
+	return &object.Quote{Node: newIdentifier(freshName(prefix))}
+}
+
+// 重命名宏展开结果中由宏本身引入的绑定，args为宏调用的参数
+// origins为unquote插入的拷贝节点对应的原节点，参数的拷贝同样来自调用处
+func hygienic(expansion ast.Node, args []*object.Quote, origins map[ast.Node]ast.Node) ast.Node {
+	h := &hygiene{
+		userNodes: make(map[ast.Node]bool),
+		targets:   make(map[*ast.Identifier]string),
+		params:    make(map[*ast.FunctionLiteral]map[string]string),
+		calls:     make(map[*ast.NamedArgument]*ast.FunctionLiteral),
+	}
+	// 来自调用处的节点
+	for _, arg := range args {
+		ast.Inspect(arg.Node, func(node ast.Node) bool {
+			if node != nil {
+				h.userNodes[node] = true
+			}
+			return true
+		})
+	}
+	for copied, original := range origins {
+		if h.userNodes[original] {
+			h.userNodes[copied] = true
+		}
+	}
+
+	root := newHygieneScope(nil, false)
+	if stmt, ok := expansion.(ast.Statement); ok {
+		h.block([]ast.Statement{stmt}, root)
+	} else {
+		h.walk(expansion, root)
+	}
+	if len(h.targets) == 0 {
+		return expansion
+	}
+
+	// 命名参数和参数默认值跟随被重命名的参数
+	for arg, fn := range h.calls {
+		if fresh, ok := h.params[fn][arg.Name.Value]; ok {
+			h.targets[arg.Name] = fresh
+		}
+	}
+	for fn, renames := range h.params {
+		if len(fn.Defaults) == 0 {
+			continue
+		}
+		defaults := make(map[string]ast.Expression, len(fn.Defaults))
+		for name, def := range fn.Defaults {
+			if fresh, ok := renames[name]; ok {
+				name = fresh
+			}
+			defaults[name] = def
+		}
+		fn.Defaults = defaults
+	}
+
+	return ast.Modify(expansion, func(node ast.Node) ast.Node {
+		ident, ok := node.(*ast.Identifier)
+		if !ok {
+			return node
+		}
+		if fresh, ok := h.targets[ident]; ok {
+			renamed := newIdentifier(fresh)
+			renamed.Token.Line, renamed.Token.Column = ident.Token.Line, ident.Token.Column
+			return renamed
+		}
+		return node
+	})
+}
+
+// 一次宏展开的重命名状态
+type hygiene struct {
+	userNodes map[ast.Node]bool
+	targets   map[*ast.Identifier]string                  // 需要重命名的标识符 -> 新名称
+	params    map[*ast.FunctionLiteral]map[string]string  // 函数中被重命名的参数 原名称 -> 新名称
+	calls     map[*ast.NamedArgument]*ast.FunctionLiteral // 命名参数所调用的宏引入的函数
+}
+
+// 宏引入的绑定，同一作用域中的同名绑定共用一个新名称
+type hygieneBinding struct {
+	fresh string
+	fn    *ast.FunctionLiteral // let绑定的函数字面量，用于重命名命名参数
+}
+
+// 函数参数、代码块、match分支和catch参数各是一个作用域
+type hygieneScope struct {
+	outer    *hygieneScope
+	function bool // 函数参数的作用域，函数体中的引用在调用时才求值
+	names    map[string]*hygieneBinding
+	bound    map[string]bool // 已经到达绑定位置的名称
+}
+
+func newHygieneScope(outer *hygieneScope, function bool) *hygieneScope {
+	return &hygieneScope{
+		outer:    outer,
+		function: function,
+		names:    make(map[string]*hygieneBinding),
+		bound:    make(map[string]bool),
+	}
+}
+
+// 查找引用对应的绑定。绑定之前的引用指向外层，例如let first = first(xs)中右侧的first；
+// 函数体中的引用在调用时才求值，可以看到外层作用域中之后的绑定，例如递归函数
+func (s *hygieneScope) resolve(name string) (*hygieneBinding, bool) {
+	delayed := false
+	for scope := s; scope != nil; scope = scope.outer {
+		if b, ok := scope.names[name]; ok && (delayed || scope.bound[name]) {
+			return b, true
+		}
+		if scope.function {
+			delayed = true
+		}
+	}
+	return nil, false
+}
+
+// 绑定宏引入的名称，返回nil表示该名称不需要重命名
+func (h *hygiene) bind(ident *ast.Identifier, s *hygieneScope) *hygieneBinding {
+	if ident == nil || h.userNodes[ident] || ident.Value == "_" {
+		return nil
+	}
+	b, ok := s.names[ident.Value]
+	if !ok {
+		b = &hygieneBinding{fresh: freshName(ident.Value)}
+		s.names[ident.Value] = b
+	}
+	s.bound[ident.Value] = true
+	h.targets[ident] = b.fresh
+	return b
+}
+
+// 代码块中的let在块内的任何位置都已知，函数体中的引用可以看到之后的绑定
+func (h *hygiene) block(statements []ast.Statement, s *hygieneScope) {
+	for _, stmt := range statements {
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok || h.userNodes[let] {
+			continue
+		}
+		for _, binder := range binders(let) {
+			if !h.userNodes[binder] && binder.Value != "_" && s.names[binder.Value] == nil {
+				s.names[binder.Value] = &hygieneBinding{fresh: freshName(binder.Value)}
+			}
+		}
+	}
+	for _, stmt := range statements {
+		h.walk(stmt, s)
+	}
+}
+
+func (h *hygiene) walk(node ast.Node, s *hygieneScope) {
+	if node == nil || h.userNodes[node] {
+		return
+	}
+
+	switch nodeT := node.(type) {
+	case *ast.Identifier:
+		if b, ok := s.resolve(nodeT.Value); ok {
+			h.targets[nodeT] = b.fresh
+		}
+	case *ast.Program:
+		h.block(nodeT.Statements, newHygieneScope(s, false))
+	case *ast.BlockStatement:
+		h.block(nodeT.Statements, newHygieneScope(s, false))
+	case *ast.LetStatement:
+		h.walk(nodeT.Value, s)
+		for _, binder := range binders(nodeT) {
+			if b := h.bind(binder, s); b != nil && nodeT.Pattern == nil {
+				b.fn, _ = nodeT.Value.(*ast.FunctionLiteral)
+			}
+		}
+	case *ast.FunctionLiteral:
+		params := newHygieneScope(s, true)
+		for _, binder := range binders(nodeT) {
+			if b := h.bind(binder, params); b != nil {
+				if h.params[nodeT] == nil {
+					h.params[nodeT] = make(map[string]string)
+				}
+				h.params[nodeT][binder.Value] = b.fresh
+			}
+		}
+		for _, def := range nodeT.Defaults {
+			h.walk(def, params)
+		}
+		h.walk(nodeT.Body, params)
+	case *ast.MatchArm:
+		arm := newHygieneScope(s, false)
+		for _, binder := range binders(nodeT) {
+			h.bind(binder, arm)
+		}
+		if nodeT.Guard != nil {
+			h.walk(nodeT.Guard, arm)
+		}
+		h.walk(nodeT.Body, arm)
+	case *ast.TryExpression:
+		h.walk(nodeT.Block, s)
+		if nodeT.Catch != nil {
+			catch := newHygieneScope(s, false)
+			h.bind(nodeT.CatchParam, catch)
+			h.walk(nodeT.Catch, catch)
+		}
+		if nodeT.Finally != nil {
+			h.walk(nodeT.Finally, s)
+		}
+	case *ast.CallExpression:
+		h.walk(nodeT.Function, s)
+		for _, arg := range nodeT.Arguments {
+			h.walk(arg, s)
+		}
+		fn := h.callee(nodeT.Function, s)
+		for _, arg := range nodeT.NamedArguments {
+			if h.userNodes[arg] {
+				continue
+			}
+			if fn != nil && !h.userNodes[arg.Name] {
+				h.calls[arg] = fn
+			}
+			h.walk(arg.Value, s)
+		}
+	case *ast.NamedArgument:
+		h.walk(nodeT.Value, s)
+	case *ast.DotExpression:
+		// 字段名不是变量引用
+		h.walk(nodeT.Left, s)
+	case *ast.StructStatement, *ast.MacroLiteral:
+		// 结构体的名称和字段不重命名，宏字面量在展开时报错
+	default:
+		for _, child := range ast.Children(node) {
+			h.walk(child, s)
+		}
+	}
+}
+
+// 被调用的宏引入的函数：直接调用的函数字面量，或者let绑定了函数字面量的名称
+func (h *hygiene) callee(function ast.Expression, s *hygieneScope) *ast.FunctionLiteral {
+	if h.userNodes[function] {
+		return nil
+	}
+	switch functionT := function.(type) {
+	case *ast.FunctionLiteral:
+		return functionT
+	case *ast.Identifier:
+		if b, ok := s.resolve(functionT.Value); ok {
+			return b.fn
+		}
+	}
+	return nil
+}
+
+// 节点中不是变量引用的标识符：字段名和命名参数的名称
+func nonVariables(node ast.Node) []*ast.Identifier {
+	switch nodeT := node.(type) {
+	case *ast.DotExpression:
+		return []*ast.Identifier{nodeT.Name}
+	case *ast.NamedArgument:
+		return []*ast.Identifier{nodeT.Name}
+	case *ast.StructStatement:
+		return nodeT.Fields
+	}
+	return nil
+}
+
+// 节点直接引入的绑定
+func binders(node ast.Node) []*ast.Identifier {
+	switch nodeT := node.(type) {
+	case *ast.LetStatement:
+		if nodeT.Pattern != nil {
+			return patternBinders(nodeT.Pattern)
+		}
+		return []*ast.Identifier{nodeT.Name}
+	case *ast.FunctionLiteral:
+		binders := append([]*ast.Identifier{}, nodeT.Parameters...)
+		if nodeT.Rest != nil {
+			binders = append(binders, nodeT.Rest)
+		}
+		return binders
+	case *ast.TryExpression:
+		if nodeT.CatchParam != nil {
+			return []*ast.Identifier{nodeT.CatchParam}
+		}
+	case *ast.MatchArm:
+		return patternBinders(nodeT.Pattern)
+	}
+	return nil
+}
+
+func patternBinders(pattern ast.Expression) []*ast.Identifier {
+	var binders []*ast.Identifier
+
+	switch patternT := pattern.(type) {
+	case *ast.Identifier:
+		binders = append(binders, patternT)
+	case *ast.ArrayPattern:
+		for _, el := range patternT.Elements {
+			binders = append(binders, patternBinders(el)...)
+		}
+		if patternT.Rest != nil {
+			binders = append(binders, patternT.Rest)
+		}
+	case *ast.HashPattern:
+		for _, val := range patternT.Values {
+			binders = append(binders, patternBinders(val)...)
+		}
+	}
+	return binders
+}
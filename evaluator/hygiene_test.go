@@ -0,0 +1,160 @@
+package evaluator
+
+import (
+	"strings"
+	"testing"
+
+	"monkey/ast"
+	"monkey/format"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+)
+
+func testExpandAndEval(t *testing.T, input string) object.Object {
+	t.Helper()
+
+	program := testParseProgram(input)
+	env := object.NewEnvironment()
+	program = DefineMacros(program, env)
+	expanded, diagnostics := ExpandMacros(program, env)
+	if len(diagnostics) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", diagnostics)
+	}
+	return Eval(expanded, env)
+}
+
+func TestMacroHygiene(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		// 宏引入的参数不会捕获调用处的同名变量
+		{`let withTemp = macro(body) { quote(fn(tmp) { unquote(body) }(1)) };
+let tmp = 10; withTemp(tmp + 1);`, 11},
+		{`let scaled = macro(e) { quote(fn() { let result = 2; unquote(e) * result }()) };
+let result = 10; scaled(result);`, 20},
+		// 宏体内对自己引入的绑定的引用一起重命名
+		{`let twice = macro(x) { quote(fn(v) { v + v }(unquote(x))) };
+let v = 5; twice(v * 2);`, 20},
+		{`let head = macro(arr) { quote(match (unquote(arr)) { [n, ...rest] => n + len(rest), _ => 0 }) };
+let n = 100; let rest = 100; head([n, 2, 3]);`, 102},
+		{`let safe = macro(e) { quote(try { unquote(e) } catch (err) { 0 }) };
+let err = 7; safe(err + 1);`, 8},
+		// 字段名和命名参数的名称不是变量，不会被重命名
+		{`let getX = macro(h) { quote(fn(x) { unquote(h).x + x }(1)) };
+getX({"x": 10});`, 11},
+		{`let call = macro(f) { quote(fn(a) { unquote(f)(a: a) }(5)) };
+call(fn(a) { a * 2 });`, 10},
+		// 多次插入的参数都是调用处的代码，不会被重命名
+		{`let withTemp = macro(e) { quote(fn(tmp) { unquote(e) + unquote(e) + tmp }(1)) };
+let tmp = 10; withTemp(tmp);`, 21},
+		// 宏体中的自由变量仍然引用调用处的环境
+		{`let addOffset = macro(e) { quote(unquote(e) + offset) };
+let offset = 3; addOffset(4);`, 7},
+		// 按作用域重命名：绑定之前的同名引用指向外层，这里是内置函数first
+		{`let m = macro(xs) { quote(fn() { let first = first(unquote(xs)); first }()) };
+m([7, 2]);`, 7},
+		// 参数只重命名函数内的引用，外层的同名变量保持原样
+		{`let x = 100;
+let m = macro(a) { quote(fn() { let g = fn(x) { x * 2 }; g(unquote(a)) + x }()) };
+m(1);`, 102},
+		// 函数体中可以引用之后的绑定，例如递归
+		{`let m = macro(n) { quote(fn() { let count = fn(i) { if (i > 0) { count(i - 1) } else { 0 } }; count(unquote(n)) + 1 }()) };
+m(3);`, 1},
+		// 命名参数和默认值跟随被重命名的参数
+		{`let m = macro(a) { quote(fn() { let f = fn(n, k = 1) { n * k }; f(unquote(a), k: 3) }()) };
+m(2);`, 6},
+		{`let m = macro(a) { quote(fn(n, k = 10) { n + k }(unquote(a))) };
+m(2);`, 12},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testExpandAndEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestHygienicExpansionNames(t *testing.T) {
+	program := testParseProgram(`let withTemp = macro(body) { quote(fn(tmp) { unquote(body) }) };
+withTemp(tmp);`)
+	env := object.NewEnvironment()
+	program = DefineMacros(program, env)
+	expanded, _ := ExpandMacros(program, env)
+
+	fn := expanded.(*ast.Program).Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	param := fn.Parameters[0].Value
+	if !strings.HasPrefix(param, "tmp__") {
+		t.Errorf("parameter not renamed. got=%q", param)
+	}
+	if body := fn.Body.String(); body != "tmp" {
+		t.Errorf("user identifier should keep its name. got=%q", body)
+	}
+}
+
+func TestGensym(t *testing.T) {
+	first, ok := gensym(&object.String{Value: "tmp"}).(*object.Quote)
+	if !ok {
+		t.Fatalf("gensym should return a Quote")
+	}
+	second := gensym(&object.String{Value: "tmp"}).(*object.Quote)
+
+	a, ok := first.Node.(*ast.Identifier)
+	if !ok {
+		t.Fatalf("quote node is not *ast.Identifier. got=%T", first.Node)
+	}
+	b := second.Node.(*ast.Identifier)
+	if !strings.HasPrefix(a.Value, "tmp__") || a.Value == b.Value {
+		t.Errorf("gensym should return fresh names. got=%q and %q", a.Value, b.Value)
+	}
+
+	// 生成的名称可以通过unquote插入到quote中
+	evaluated := testEval(`let g = gensym(); quote(unquote(g) + 1)`)
+	quote, ok := evaluated.(*object.Quote)
+	if !ok {
+		t.Fatalf("expected *object.Quote. got=%T (%+v)", evaluated, evaluated)
+	}
+	if !strings.HasPrefix(quote.Node.String(), "(g__") {
+		t.Errorf("wrong quoted node. got=%q", quote.Node.String())
+	}
+
+	if err, ok := gensym(&object.Integer{Value: 1}).(*object.Error); !ok || err.Message != "argument to `gensym` must be STRING, got INTEGER" {
+		t.Errorf("expected type error. got=%+v", err)
+	}
+}
+
+func TestFreshNamesRoundTrip(t *testing.T) {
+	input := `let twice = macro(x) { quote(fn(tmp) { tmp + tmp }(unquote(x))) };
+let tmp = 5;
+twice(tmp);`
+	program := testParseProgram(input)
+	env := object.NewEnvironment()
+	program = DefineMacros(program, env)
+	expanded, diagnostics := ExpandMacros(program, env)
+	if len(diagnostics) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", diagnostics)
+	}
+
+	statements := expanded.(*ast.Program).Statements
+	fn := statements[len(statements)-1].(*ast.ExpressionStatement).Expression.(*ast.CallExpression).Function.(*ast.FunctionLiteral)
+	if param := fn.Parameters[0].Value; !isIdentifierName(param) {
+		t.Errorf("generated name is not a valid identifier. got=%q", param)
+	}
+
+	// 展开后的代码格式化之后可以重新解析，结果相同
+	src := format.Node(expanded)
+	p := parser.New(lexer.New(src))
+	reparsed := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("cannot parse expanded code %q: %v", src, p.Errors())
+	}
+	testIntegerObject(t, Eval(reparsed, object.NewEnvironment()), 10)
+}
+
+func TestLetterCount(t *testing.T) {
+	tests := map[int64]string{1: "a", 26: "z", 27: "aa", 52: "az", 703: "aaa"}
+	for n, expected := range tests {
+		if got := letterCount(n); got != expected {
+			t.Errorf("letterCount(%d) wrong. want=%q, got=%q", n, expected, got)
+		}
+	}
+}
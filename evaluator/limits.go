@@ -0,0 +1,84 @@
+package evaluator
+
+import (
+	"fmt"
+
+	"monkey/object"
+)
+
+// Limits 执行限制，用于运行不可信的代码。值为0表示不限制
+type Limits struct {
+	MaxSteps  int // 最多对多少个AST节点求值
+	MaxDepth  int // 函数调用的最大嵌套深度
+	MaxAllocs int // 累计分配的近似字节数
+}
+
+// SetLimits 设置执行限制，同时清空已经使用的额度
+func (e *Evaluator) SetLimits(limits Limits) {
+	e.limits = limits
+	e.Reset()
+}
+
+// Reset 清空已经使用的额度、调用栈和警告，宿主程序在每次独立的执行前调用
+func (e *Evaluator) Reset() {
+	e.steps = 0
+	e.depth = 0
+	e.allocated = 0
+	e.stack = nil
+	e.warnings = nil
+}
+
+func newLimitError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...), Kind: object.LIMIT_ERROR}
+}
+
+// 每对一个节点求值消耗一步
+func (e *Evaluator) step() *object.Error {
+	e.steps++
+	if e.limits.MaxSteps > 0 && e.steps > e.limits.MaxSteps {
+		return newLimitError("step limit exceeded: %d", e.limits.MaxSteps)
+	}
+	return nil
+}
+
+// 进入函数调用，与leaveCall成对使用
+func (e *Evaluator) enterCall() *object.Error {
+	e.depth++
+	if e.limits.MaxDepth > 0 && e.depth > e.limits.MaxDepth {
+		return newLimitError("call depth limit exceeded: %d", e.limits.MaxDepth)
+	}
+	return nil
+}
+
+func (e *Evaluator) leaveCall() {
+	e.depth--
+}
+
+// 记录新创建对象的近似大小，超出额度时返回错误对象代替原对象
+func (e *Evaluator) track(obj object.Object) object.Object {
+	e.allocated += sizeOf(obj)
+	if e.limits.MaxAllocs > 0 && e.allocated > e.limits.MaxAllocs {
+		return newLimitError("allocation limit exceeded: %d bytes", e.limits.MaxAllocs)
+	}
+	return obj
+}
+
+// 近似的对象大小，元素本身在创建时已经计算过，这里只计算容器自身
+func sizeOf(obj object.Object) int {
+	switch objT := obj.(type) {
+	case *object.Integer:
+		return 8
+	case *object.String:
+		return 16 + len(objT.Value)
+	case *object.Array:
+		return 24 + 8*len(objT.Elements)
+	case *object.Hash:
+		return 48 + 64*len(objT.Pairs)
+	case *object.Function:
+		return 64
+	case *object.Instance:
+		return 16 + 16*len(objT.Fields)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,68 @@
+package evaluator
+
+import (
+	"testing"
+
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+)
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		input           string
+		limits          Limits
+		expectedMessage string
+	}{
+		{
+			"let f = fn(x) { 1 + f(x + 1) }; f(0);",
+			Limits{MaxDepth: 50},
+			"call depth limit exceeded: 50",
+		},
+		{
+			"let f = fn(x) { if (x > 0) { f(x - 1) } else { 0 } }; f(1000);",
+			Limits{MaxSteps: 100},
+			"step limit exceeded: 100",
+		},
+		{
+			`let grow = fn(s, n) { if (n > 0) { grow(s + s, n - 1) } else { s } }; grow("ab", 20);`,
+			Limits{MaxAllocs: 10000},
+			"allocation limit exceeded: 10000 bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		e := New()
+		e.SetLimits(tt.limits)
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		evaluated := e.Eval(program, object.NewEnvironment())
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Kind != object.LIMIT_ERROR {
+			t.Errorf("wrong error kind. got=%q", errObj.Kind)
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestLimitsNotExceeded(t *testing.T) {
+	e := New()
+	e.SetLimits(Limits{MaxSteps: 1000, MaxDepth: 20, MaxAllocs: 10000})
+
+	input := "let f = fn(x) { if (x > 0) { f(x - 1) } else { 42 } }; f(10);"
+	program := parser.New(lexer.New(input)).ParseProgram()
+	testIntegerObject(t, e.Eval(program, object.NewEnvironment()), 42)
+
+	// 调用结束后深度恢复
+	if e.depth != 0 {
+		t.Errorf("depth not restored. got=%d", e.depth)
+	}
+}
@@ -1,6 +1,8 @@
 package evaluator
 
 import (
+	"fmt"
+
 	"monkey/ast"
 	"monkey/object"
 )
@@ -8,7 +10,7 @@ import (
 // 什么是有效的宏
 func isMacroDefinition(node ast.Statement) bool {
 	letStatement, ok := node.(*ast.LetStatement)
-	if !ok {
+	if !ok || letStatement.Name == nil {
 		return false
 	}
 
@@ -16,6 +18,28 @@ func isMacroDefinition(node ast.Statement) bool {
 	return ok
 }
 
+// 查找宏定义放入env，返回其余的语句，statements本身不会被修改
+func defineMacros(statements []ast.Statement, env *object.Environment) []ast.Statement {
+	remaining := make([]ast.Statement, 0, len(statements))
+	for _, statement := range statements {
+		if isMacroDefinition(statement) {
+			addMacro(statement, env)
+			continue
+		}
+		remaining = append(remaining, statement)
+	}
+	return remaining
+}
+
+func hasMacroDefinition(statements []ast.Statement) bool {
+	for _, statement := range statements {
+		if isMacroDefinition(statement) {
+			return true
+		}
+	}
+	return false
+}
+
 func addMacro(stmt ast.Statement, env *object.Environment) {
 	letStatement, _ := stmt.(*ast.LetStatement)
 	macroLiteral, _ := letStatement.Value.(*ast.MacroLiteral)
@@ -28,30 +52,270 @@ func addMacro(stmt ast.Statement, env *object.Environment) {
 	env.Set(letStatement.Name.Value, macro)
 }
 
-// 宏展开，用求值结果替换了宏调用
-func ExpandMacros(program ast.Node, env *object.Environment) ast.Node {
+// Diagnostic 宏展开时产生的错误，位置为宏调用所在的位置
+type Diagnostic struct {
+	Message string
+	Line    int
+	Column  int
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("line %d, column %d: %s", d.Line, d.Column, d.Message)
+}
+
+// 宏展开的最大嵌套深度，宏的展开结果中又包含宏调用时深度加一，用于发现无限展开
+const maxMacroDepth = 100
+
+// 一次展开中最多展开的宏调用数，展开结果中包含多个宏调用时，深度不大也可能无限展开
+const maxMacroExpansions = 10000
+
+// 宏展开，用求值结果替换了宏调用，展开结果中的宏调用继续展开，直到不再有宏调用
+// quote中的代码不会被展开，展开在program的拷贝上进行，program本身不会被修改
+// 展开失败的宏调用保持原样，并返回对应的诊断信息，此时不应继续求值
+func ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, []Diagnostic) {
+	return New().ExpandMacros(program, env)
+}
+
+// ExpandMacros 展开宏，并记录宏所在的环境供macroexpand使用
+func (e *Evaluator) ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, []Diagnostic) {
+	e.macroEnv = env
+	budget := maxMacroExpansions
+	return e.expandMacros(ast.Clone(program), env, 0, &budget)
+}
+
+// budget为剩余可以展开的宏调用数，用完或者超过嵌套深度之后只报告一次错误，其余的宏调用保持原样
+func (e *Evaluator) expandMacros(node ast.Node, env *object.Environment, depth int, budget *int) (ast.Node, []Diagnostic) {
+	scopes, diagnostics := e.macroScopes(node, env)
+
 	// 递归遍历AST
-	return ast.Modify(program, func(node ast.Node) ast.Node {
+	expanded := ast.Modify(node, func(node ast.Node) ast.Node {
 		callExpression, ok := node.(*ast.CallExpression)
 		if !ok {
 			return node
 		}
+		// quote中的调用没有作用域
+		scope, ok := scopes[callExpression]
+		if !ok {
+			return node
+		}
 
-		macro, ok := isMacroCall(callExpression, env)
+		macro, ok := isMacroCall(callExpression, scope)
 		if !ok {
 			return node
 		}
 
-		args := quoteArgs(callExpression)
-		evalEnv := extendMacroEnv(macro, args)
-		evaluated := Eval(macro.Body, evalEnv)
+		pos := callExpression.Function.(*ast.Identifier).Token
+		if *budget <= 0 {
+			if *budget == 0 {
+				diagnostics = append(diagnostics, Diagnostic{
+					Message: fmt.Sprintf("macro expansion limit exceeded: %d expansions", maxMacroExpansions),
+					Line:    pos.Line,
+					Column:  pos.Column,
+				})
+				*budget = -1
+			}
+			return node
+		}
+		*budget--
+		if depth >= maxMacroDepth {
+			diagnostics = append(diagnostics, Diagnostic{
+				Message: fmt.Sprintf("macro expansion depth limit exceeded: %d", maxMacroDepth),
+				Line:    pos.Line,
+				Column:  pos.Column,
+			})
+			*budget = -1
+			return node
+		}
 
-		quoteObj, ok := evaluated.(*object.Quote)
-		if !ok {
-			panic("we only support returning AST-nodes from macros")
+		expansion, err := e.expandMacroCall(callExpression, macro)
+		if err != nil {
+			diagnostics = append(diagnostics, Diagnostic{Message: err.Message, Line: pos.Line, Column: pos.Column})
+			return node
 		}
-		return quoteObj.Node
+
+		// 展开结果中可能还有宏调用，在调用处的作用域中继续展开
+		expansion, nested := e.expandMacros(expansion, scope, depth+1, budget)
+		diagnostics = append(diagnostics, nested...)
+		return expansion
 	})
+	return expanded, diagnostics
+}
+
+// 宏的作用域，代码块中定义的宏只在该代码块内可见，并遮蔽外层的同名宏
+type macroScope struct {
+	e           *Evaluator // 用于加载导入的模块
+	env         *object.Environment
+	quoted      map[ast.Node]bool
+	scopes      map[*ast.CallExpression]*object.Environment
+	diagnostics *[]Diagnostic
+}
+
+// 取出node中各个代码块的宏定义和导入的宏，返回每个调用所在的作用域，
+// 以及没有用let绑定的宏字面量和无法加载的模块
+func (e *Evaluator) macroScopes(node ast.Node, env *object.Environment) (map[*ast.CallExpression]*object.Environment, []Diagnostic) {
+	var diagnostics []Diagnostic
+	scope := &macroScope{
+		e:           e,
+		env:         env,
+		quoted:      quotedNodes(node),
+		scopes:      make(map[*ast.CallExpression]*object.Environment),
+		diagnostics: &diagnostics,
+	}
+	ast.Walk(scope, node)
+	return scope.scopes, diagnostics
+}
+
+func (s *macroScope) Visit(node ast.Node) ast.Visitor {
+	if node == nil {
+		return nil
+	}
+	// quote中的代码不展开，但其中unquote的参数会被求值，需要继续遍历
+	if s.quoted[node] {
+		return s
+	}
+
+	switch nodeT := node.(type) {
+	case *ast.Program:
+		// 顶层的宏定义和导入的宏在整个解释器中可见，同名时代码块中的定义优先
+		*s.diagnostics = append(*s.diagnostics, s.e.importMacros(nodeT.Statements, s.env)...)
+		nodeT.Statements = defineMacros(nodeT.Statements, s.env)
+	case *ast.BlockStatement:
+		if hasMacroDefinition(nodeT.Statements) || hasImport(nodeT.Statements) {
+			inner := *s
+			inner.env = object.NewEnclosedEnvironment(s.env)
+			*s.diagnostics = append(*s.diagnostics, s.e.importMacros(nodeT.Statements, inner.env)...)
+			nodeT.Statements = defineMacros(nodeT.Statements, inner.env)
+			return &inner
+		}
+	case *ast.CallExpression:
+		s.scopes[nodeT] = s.env
+	case *ast.MacroLiteral:
+		*s.diagnostics = append(*s.diagnostics, Diagnostic{
+			Message: "macro literal must be bound with `let`",
+			Line:    nodeT.Token.Line,
+			Column:  nodeT.Token.Column,
+		})
+		return nil
+	}
+	return s
+}
+
+// quote调用内部的节点，unquote和unquote_splice的参数会被求值，不属于quote
+func quotedNodes(node ast.Node) map[ast.Node]bool {
+	quoted := make(map[ast.Node]bool)
+	findQuotes(node, quoted)
+	return quoted
+}
+
+// 找出node中的quote调用，标记其参数
+func findQuotes(node ast.Node, quoted map[ast.Node]bool) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpression)
+		if !ok || call.Function.TokenLiteral() != "quote" {
+			return n != nil
+		}
+		for _, arg := range call.Arguments {
+			markQuoted(arg, quoted)
+		}
+		return false
+	})
+}
+
+func markQuoted(node ast.Node, quoted map[ast.Node]bool) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n == nil {
+			return false
+		}
+		quoted[n] = true
+		if isUnquoteCall(n) || isUnquoteSpliceCall(n) {
+			for _, arg := range n.(*ast.CallExpression).Arguments {
+				findQuotes(arg, quoted)
+			}
+			return false
+		}
+		return true
+	})
+}
+
+// macroexpand(quote(expr)) 返回完全展开后的quote
+func (e *Evaluator) macroexpand(args ...object.Object) object.Object {
+	if err := CheckArgs("macroexpand", args, object.QUOTE_OBJ); err != nil {
+		return err
+	}
+	if e.macroEnv == nil {
+		return errNoMacroEnv("macroexpand")
+	}
+
+	budget := maxMacroExpansions
+	expanded, diagnostics := e.expandMacros(ast.Clone(args[0].(*object.Quote).Node), e.macroEnv, 0, &budget)
+	if len(diagnostics) != 0 {
+		return newError("%s", diagnostics[0].String())
+	}
+	return &object.Quote{Node: expanded}
+}
+
+// macroexpand1(quote(expr)) 只展开一次最外层的宏调用，不是宏调用时原样返回
+func (e *Evaluator) macroexpand1(args ...object.Object) object.Object {
+	if err := CheckArgs("macroexpand1", args, object.QUOTE_OBJ); err != nil {
+		return err
+	}
+	if e.macroEnv == nil {
+		return errNoMacroEnv("macroexpand1")
+	}
+
+	call, ok := args[0].(*object.Quote).Node.(*ast.CallExpression)
+	if !ok {
+		return args[0]
+	}
+	macro, ok := isMacroCall(call, e.macroEnv)
+	if !ok {
+		return args[0]
+	}
+
+	expansion, err := e.expandMacroCall(call, macro)
+	if err != nil {
+		return err
+	}
+	return &object.Quote{Node: expansion}
+}
+
+// 求值器没有展开过宏时不知道宏定义在哪里，例如直接调用Eval
+func errNoMacroEnv(name string) *object.Error {
+	return newError("`%s` is only available after macros are expanded by the same evaluator", name)
+}
+
+// 对宏调用求值，返回替换宏调用的节点
+func (e *Evaluator) expandMacroCall(call *ast.CallExpression, macro *object.Macro) (ast.Node, *object.Error) {
+	name := call.Function.(*ast.Identifier).Value
+
+	if len(call.NamedArguments) > 0 {
+		return nil, newError("macro `%s` does not accept named arguments", name)
+	}
+	if len(call.Arguments) != len(macro.Parameters) {
+		return nil, newError("wrong number of arguments to macro `%s`: want=%d, got=%d",
+			name, len(macro.Parameters), len(call.Arguments))
+	}
+
+	// 记录宏体中unquote插入的拷贝，嵌套的宏展开共用同一个记录
+	if e.origins == nil {
+		e.origins = make(map[ast.Node]ast.Node)
+		defer func() { e.origins = nil }()
+	}
+
+	args := quoteArgs(call)
+	evalEnv := extendMacroEnv(macro, args)
+	evaluated := e.Eval(macro.Body, evalEnv)
+
+	switch evaluatedT := unWarpReturnValue(evaluated).(type) {
+	case *object.Quote:
+		return hygienic(evaluatedT.Node, args, e.origins), nil
+	case *object.Error:
+		return nil, newError("error in macro `%s`: %s", name, evaluatedT.Message)
+	case nil:
+		return nil, newError("macro `%s` must return a quoted AST node, got nothing", name)
+	default:
+		return nil, newError("macro `%s` must return a quoted AST node, got %s", name, evaluatedT.Type())
+	}
 }
 
 func isMacroCall(exp *ast.CallExpression, env *object.Environment) (*object.Macro, bool) {
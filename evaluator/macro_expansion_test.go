@@ -18,10 +18,14 @@ let mymacro = macro(x,y){x+y;};
 
 	env := object.NewEnvironment()
 	program := testParseProgram(input)
-	DefineMacros(program, env)
+	defined := DefineMacros(program, env)
 
-	if len(program.Statements) != 2 {
-		t.Fatalf("Wrong number of statements. got=%d", len(program.Statements))
+	if len(defined.Statements) != 2 {
+		t.Fatalf("Wrong number of statements. got=%d", len(defined.Statements))
+	}
+	// 传入的程序保持不变
+	if len(program.Statements) != 3 {
+		t.Fatalf("DefineMacros modified the program. got=%d statements", len(program.Statements))
 	}
 
 	_, ok := env.Get("number")
@@ -84,6 +88,11 @@ func TestExpandMacros(t *testing.T) {
                 };
                 unless(10>5,puts("not greater"),puts("greater"));`,
 			`if (!(10>5)){puts("not greater")}else{puts("greater")}`},
+		// 嵌套在调用参数中的宏调用
+		{`let double = macro(a){quote(unquote(a) * 2);};let triple = macro(a){quote(unquote(a) * 3);};puts(double(3), [triple(4)]);`, `puts(3 * 2, [4 * 3])`},
+		{`let double = macro(a){quote(unquote(a) * 2);};let triple = macro(a){quote(unquote(a) * 3);};double(triple(1));`, `(1 * 3) * 2`},
+		// 同一个宏可以多次使用
+		{`let double = macro(a){quote(unquote(a) * 2);};double(1) + double(double(2));`, `(1 * 2) + ((2 * 2) * 2)`},
 	}
 
 	for _, tt := range tests {
@@ -91,11 +100,275 @@ func TestExpandMacros(t *testing.T) {
 		program := testParseProgram(tt.input)
 
 		env := object.NewEnvironment()
-		DefineMacros(program, env)
-		expaned := ExpandMacros(program, env)
+		program = DefineMacros(program, env)
+		expaned, diagnostics := ExpandMacros(program, env)
+		if len(diagnostics) != 0 {
+			t.Fatalf("unexpected diagnostics: %v", diagnostics)
+		}
 
 		if expaned.String() != expected.String() {
 			t.Errorf("not equal. want=%q, got=%q", expected.String(), expaned.String())
 		}
 	}
 }
+
+func TestExpandMacrosDiagnostics(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`let m = macro(a) { quote(unquote(a)); };
+m(1, 2);`, []string{"line 2, column 1: wrong number of arguments to macro `m`: want=1, got=2"}},
+		{`let m = macro(a) { quote(unquote(a)); }; m(a: 1);`,
+			[]string{"line 1, column 42: macro `m` does not accept named arguments"}},
+		{`let m = macro() { 1 }; m();`,
+			[]string{"line 1, column 24: macro `m` must return a quoted AST node, got INTEGER"}},
+		{`let m = macro() { }; m();`,
+			[]string{"line 1, column 22: macro `m` must return a quoted AST node, got nothing"}},
+		{`let m = macro() { 1 + true }; m();`,
+			[]string{"line 1, column 31: error in macro `m`: type mismatch: INTEGER + BOOLEAN"}},
+		{`let m = macro() { 1 }; m(); m();`, []string{
+			"line 1, column 24: macro `m` must return a quoted AST node, got INTEGER",
+			"line 1, column 29: macro `m` must return a quoted AST node, got INTEGER",
+		}},
+	}
+
+	for _, tt := range tests {
+		program := testParseProgram(tt.input)
+
+		env := object.NewEnvironment()
+		program = DefineMacros(program, env)
+		_, diagnostics := ExpandMacros(program, env)
+
+		if len(diagnostics) != len(tt.expected) {
+			t.Errorf("wrong number of diagnostics for %q. want=%d, got=%v", tt.input, len(tt.expected), diagnostics)
+			continue
+		}
+		for i, d := range diagnostics {
+			if d.String() != tt.expected[i] {
+				t.Errorf("wrong diagnostic. want=%q, got=%q", tt.expected[i], d.String())
+			}
+		}
+	}
+}
+
+func TestExpandMacrosFixedPoint(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// 宏的展开结果中包含另一个宏调用
+		{`let unless = macro(c, a) { quote(if (!(unquote(c))) { unquote(a) }); };
+let when = macro(c, a) { quote(unless(!(unquote(c)), unquote(a))); };
+when(x > 1, 5);`, `if (!(!(x > 1))) { 5 }`},
+		// quote中的宏调用不展开
+		{`let double = macro(a) { quote(unquote(a) * 2); }; quote(double(1));`, `quote(double(1))`},
+		// unquote的参数会被求值，其中的宏调用需要展开
+		{`let d = macro(a) { quote(unquote(a) * 2); }; quote(1 + unquote(d(3)));`, `quote(1 + unquote(3 * 2))`},
+		{`let d = macro(a) { quote(unquote(a) * 2); }; quote(f(unquote_splice([d(3)]), quote(d(4))));`, `quote(f(unquote_splice([3 * 2]), quote(d(4))))`},
+	}
+
+	for _, tt := range tests {
+		expected := testParseProgram(tt.expected)
+		program := testParseProgram(tt.input)
+
+		env := object.NewEnvironment()
+		program = DefineMacros(program, env)
+		expanded, diagnostics := ExpandMacros(program, env)
+		if len(diagnostics) != 0 {
+			t.Fatalf("unexpected diagnostics: %v", diagnostics)
+		}
+
+		if expanded.String() != expected.String() {
+			t.Errorf("not equal. want=%q, got=%q", expected.String(), expanded.String())
+		}
+	}
+}
+
+func TestExpandMacrosDepthLimit(t *testing.T) {
+	program := testParseProgram(`let loop = macro() { quote(loop()); }; loop();`)
+
+	env := object.NewEnvironment()
+	program = DefineMacros(program, env)
+	_, diagnostics := ExpandMacros(program, env)
+
+	if len(diagnostics) != 1 {
+		t.Fatalf("wrong number of diagnostics. got=%v", diagnostics)
+	}
+	expected := "line 1, column 28: macro expansion depth limit exceeded: 100"
+	if diagnostics[0].String() != expected {
+		t.Errorf("wrong diagnostic. want=%q, got=%q", expected, diagnostics[0].String())
+	}
+}
+
+func TestExpandMacrosExpansionLimit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// 每次展开产生两个宏调用，超过嵌套深度后不再展开其余的调用
+		{`let b = macro() { quote([b(), b()]); }; b();`, "macro expansion depth limit exceeded: 100"},
+		// 嵌套深度只有20，但展开次数指数增长
+		{`let b = macro(n) {
+  let m = ast_value(n);
+  if (m == 0) { quote(0) } else { quote([b(unquote(m - 1)), b(unquote(m - 1))]) }
+};
+b(20);`, "macro expansion limit exceeded: 10000 expansions"},
+	}
+
+	for _, tt := range tests {
+		program := testParseProgram(tt.input)
+		env := object.NewEnvironment()
+		program = DefineMacros(program, env)
+		_, diagnostics := ExpandMacros(program, env)
+
+		if len(diagnostics) != 1 {
+			t.Fatalf("wrong number of diagnostics. got=%d", len(diagnostics))
+		}
+		if diagnostics[0].Message != tt.expected {
+			t.Errorf("wrong diagnostic. want=%q, got=%q", tt.expected, diagnostics[0].Message)
+		}
+	}
+}
+
+func TestMacroexpandWithoutExpansion(t *testing.T) {
+	for _, name := range []string{"macroexpand", "macroexpand1"} {
+		evaluated := testEval(name + "(quote(1 + 2))")
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Fatalf("expected error from %s. got=%T (%+v)", name, evaluated, evaluated)
+		}
+		expected := "`" + name + "` is only available after macros are expanded by the same evaluator"
+		if errObj.Message != expected {
+			t.Errorf("wrong error message. want=%q, got=%q", expected, errObj.Message)
+		}
+	}
+}
+
+func TestMacroexpandBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let unless = macro(c, a) { quote(if (!(unquote(c))) { unquote(a) }); };
+let when = macro(c, a) { quote(unless(!(unquote(c)), unquote(a))); };
+macroexpand(quote(when(x > 1, 5)));`, `if (!(!(x > 1))) { 5 }`},
+		{`let unless = macro(c, a) { quote(if (!(unquote(c))) { unquote(a) }); };
+let when = macro(c, a) { quote(unless(!(unquote(c)), unquote(a))); };
+macroexpand1(quote(when(x > 1, 5)));`, `unless(!(x > 1), 5)`},
+		{`macroexpand1(quote(puts(1)));`, `puts(1)`},
+		{`macroexpand(quote(1 + 2));`, `1 + 2`},
+	}
+
+	for _, tt := range tests {
+		program := testParseProgram(tt.input)
+
+		e := New()
+		env := object.NewEnvironment()
+		macroEnv := object.NewEnvironment()
+		program = DefineMacros(program, macroEnv)
+		expanded, diagnostics := e.ExpandMacros(program, macroEnv)
+		if len(diagnostics) != 0 {
+			t.Fatalf("unexpected diagnostics: %v", diagnostics)
+		}
+
+		evaluated := e.Eval(expanded, env)
+		quote, ok := evaluated.(*object.Quote)
+		if !ok {
+			t.Fatalf("expected *object.Quote. got=%T (%+v)", evaluated, evaluated)
+		}
+		expected := testParseProgram(tt.expected).Statements[0].(*ast.ExpressionStatement).Expression
+		if quote.Node.String() != expected.String() {
+			t.Errorf("not equal. want=%q, got=%q", expected.String(), quote.Node.String())
+		}
+	}
+}
+
+func TestExpandScopedMacros(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// 函数体中定义的宏
+		{`let f = fn(x) { let double = macro(a) { quote(unquote(a) * 2); }; double(x) };`,
+			`let f = fn(x) { x * 2 };`},
+		// 代码块中的宏遮蔽外层的同名宏，并且在代码块外不可见
+		{`let m = macro(a) { quote(unquote(a) + 1); };
+if (true) { let m = macro(a) { quote(unquote(a) - 1); }; m(1) }; m(2);`,
+			`if (true) { 1 - 1 }; 2 + 1;`},
+		{`if (true) { let inner = macro() { quote(1); }; inner() } else { inner() };`,
+			`if (true) { 1 } else { inner() };`},
+		// 内层代码块使用外层代码块定义的宏
+		{`fn() { let one = macro() { quote(1); }; fn() { one() } };`, `fn() { fn() { 1 } };`},
+	}
+
+	for _, tt := range tests {
+		expected := testParseProgram(tt.expected)
+		program := testParseProgram(tt.input)
+
+		env := object.NewEnvironment()
+		program = DefineMacros(program, env)
+		expanded, diagnostics := ExpandMacros(program, env)
+		if len(diagnostics) != 0 {
+			t.Fatalf("unexpected diagnostics: %v", diagnostics)
+		}
+
+		if expanded.String() != expected.String() {
+			t.Errorf("not equal. want=%q, got=%q", expected.String(), expanded.String())
+		}
+	}
+}
+
+func TestUnboundMacroLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`puts(macro(a) { a });`, "line 1, column 6: macro literal must be bound with `let`"},
+		{`let ms = [macro() { quote(1) }];`, "line 1, column 11: macro literal must be bound with `let`"},
+		{`let f = fn() { macro() { quote(1) } };`, "line 1, column 16: macro literal must be bound with `let`"},
+	}
+
+	for _, tt := range tests {
+		program := testParseProgram(tt.input)
+
+		env := object.NewEnvironment()
+		program = DefineMacros(program, env)
+		_, diagnostics := ExpandMacros(program, env)
+
+		if len(diagnostics) != 1 || diagnostics[0].String() != tt.expected {
+			t.Errorf("wrong diagnostics for %q. want=%q, got=%v", tt.input, tt.expected, diagnostics)
+		}
+	}
+
+	// 未经宏展开直接求值
+	evaluated := testEval(`macro(a) { a }`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Message != "macro literal must be bound with `let`" {
+		t.Errorf("expected macro literal error. got=%+v", evaluated)
+	}
+}
+
+func TestExpandMacrosKeepsProgram(t *testing.T) {
+	program := testParseProgram(`let double = macro(a) { quote(unquote(a) * 2); }; let f = fn() { double(1) }; double(2);`)
+	before := program.String()
+
+	// 缓存的程序每次都在新的环境中定义宏并展开
+	for i := 0; i < 2; i++ {
+		env := object.NewEnvironment()
+		defined := DefineMacros(program, env)
+		expanded, diagnostics := ExpandMacros(defined, env)
+		if len(diagnostics) != 0 {
+			t.Fatalf("unexpected diagnostics: %v", diagnostics)
+		}
+		expected := "let f = fn()(1 * 2);(2 * 2)"
+		if expanded.String() != expected {
+			t.Errorf("wrong expansion. want=%q, got=%q", expected, expanded.String())
+		}
+		testIntegerObject(t, Eval(expanded, env), 4)
+	}
+
+	if program.String() != before {
+		t.Errorf("macro expansion modified the program.\nwant=%q\ngot=%q", before, program.String())
+	}
+}
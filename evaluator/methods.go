@@ -0,0 +1,235 @@
+package evaluator
+
+import (
+	"sort"
+	"strings"
+
+	"monkey/ast"
+	"monkey/object"
+)
+
+/*
+点运算与方法调用
+h.key 按字符串键从哈希中取值，p.x 读取结构体实例的字段
+x.method(args) 依次查找：哈希或实例中的同名成员、接收者类型的方法、以接收者为第一个参数的内置函数
+因此 arr.len()、arr.push(4) 与 len(arr)、push(arr, 4) 等价
+*/
+
+// method 接收者类型的方法，需要求值器来调用作为参数传入的函数
+type method func(e *Evaluator, receiver object.Object, args ...object.Object) object.Object
+
+// 每种对象类型的方法表
+// STRING: split(sep) upper() lower() trim() contains(sub)
+// ARRAY: map(f) filter(f) reduce(f, initial) join(sep)
+// HASH: keys() values() has(key)
+var methods map[object.ObjectType]map[string]method
+
+// 方法会调用求值器，而求值器又会查找方法表，所以在init中初始化以避免初始化循环
+func init() {
+	methods = map[object.ObjectType]map[string]method{
+		object.STRING_OBJ: {
+			"split": func(e *Evaluator, receiver object.Object, args ...object.Object) object.Object {
+				if err := CheckArgs("split", args, object.STRING_OBJ); err != nil {
+					return err
+				}
+
+				parts := strings.Split(receiver.(*object.String).Value, args[0].(*object.String).Value)
+				elements := make([]object.Object, len(parts))
+				for i, part := range parts {
+					elements[i] = &object.String{Value: part}
+				}
+				return &object.Array{Elements: elements}
+			},
+			"upper": stringMethod("upper", strings.ToUpper),
+			"lower": stringMethod("lower", strings.ToLower),
+			"trim":  stringMethod("trim", strings.TrimSpace),
+			"contains": func(e *Evaluator, receiver object.Object, args ...object.Object) object.Object {
+				if err := CheckArgs("contains", args, object.STRING_OBJ); err != nil {
+					return err
+				}
+				return nativeBoolToBooleanObject(strings.Contains(receiver.(*object.String).Value, args[0].(*object.String).Value))
+			},
+		},
+		object.ARRAY_OBJ: {
+			"map": func(e *Evaluator, receiver object.Object, args ...object.Object) object.Object {
+				if err := CheckArgCount(args, 1); err != nil {
+					return err
+				}
+
+				elements := receiver.(*object.Array).Elements
+				mapped := make([]object.Object, 0, len(elements))
+				for _, el := range elements {
+					result := e.callArgument(args[0], el)
+					if isError(result) {
+						return result
+					}
+					mapped = append(mapped, result)
+				}
+				return &object.Array{Elements: mapped}
+			},
+			"filter": func(e *Evaluator, receiver object.Object, args ...object.Object) object.Object {
+				if err := CheckArgCount(args, 1); err != nil {
+					return err
+				}
+
+				filtered := []object.Object{}
+				for _, el := range receiver.(*object.Array).Elements {
+					result := e.callArgument(args[0], el)
+					if isError(result) {
+						return result
+					}
+					if isTruthy(result) {
+						filtered = append(filtered, el)
+					}
+				}
+				return &object.Array{Elements: filtered}
+			},
+			"reduce": func(e *Evaluator, receiver object.Object, args ...object.Object) object.Object {
+				if err := CheckArgCount(args, 2); err != nil {
+					return err
+				}
+
+				acc := args[1]
+				for _, el := range receiver.(*object.Array).Elements {
+					acc = e.callArgument(args[0], acc, el)
+					if isError(acc) {
+						return acc
+					}
+				}
+				return acc
+			},
+			"join": func(e *Evaluator, receiver object.Object, args ...object.Object) object.Object {
+				if err := CheckArgs("join", args, object.STRING_OBJ); err != nil {
+					return err
+				}
+
+				elements := receiver.(*object.Array).Elements
+				parts := make([]string, len(elements))
+				for i, el := range elements {
+					parts[i] = el.Inspect()
+				}
+				return &object.String{Value: strings.Join(parts, args[0].(*object.String).Value)}
+			},
+		},
+		object.HASH_OBJ: {
+			"keys": func(e *Evaluator, receiver object.Object, args ...object.Object) object.Object {
+				if err := CheckArgCount(args, 0); err != nil {
+					return err
+				}
+
+				pairs := sortedPairs(receiver.(*object.Hash))
+				keys := make([]object.Object, len(pairs))
+				for i, pair := range pairs {
+					keys[i] = pair.Key
+				}
+				return &object.Array{Elements: keys}
+			},
+			"values": func(e *Evaluator, receiver object.Object, args ...object.Object) object.Object {
+				if err := CheckArgCount(args, 0); err != nil {
+					return err
+				}
+
+				pairs := sortedPairs(receiver.(*object.Hash))
+				values := make([]object.Object, len(pairs))
+				for i, pair := range pairs {
+					values[i] = pair.Value
+				}
+				return &object.Array{Elements: values}
+			},
+			"has": func(e *Evaluator, receiver object.Object, args ...object.Object) object.Object {
+				if err := CheckArgCount(args, 1); err != nil {
+					return err
+				}
+
+				key, ok := args[0].(object.Hashtable)
+				if !ok {
+					return newError("unusable as hash key: %s", args[0].Type())
+				}
+				_, ok = receiver.(*object.Hash).Pairs[key.HashKey()]
+				return nativeBoolToBooleanObject(ok)
+			},
+		},
+	}
+}
+
+func stringMethod(name string, fn func(string) string) method {
+	return func(e *Evaluator, receiver object.Object, args ...object.Object) object.Object {
+		if err := CheckArgs(name, args); err != nil {
+			return err
+		}
+		return &object.String{Value: fn(receiver.(*object.String).Value)}
+	}
+}
+
+// 哈希本身无序，按键的字面值排序使结果稳定
+func sortedPairs(hash *object.Hash) []object.HashPair {
+	pairs := make([]object.HashPair, 0, len(hash.Pairs))
+	for _, pair := range hash.Pairs {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Key.Inspect() < pairs[j].Key.Inspect()
+	})
+	return pairs
+}
+
+// h.key 键不存在时为null，p.x 字段不存在时为错误
+func (e *Evaluator) evalDotExpression(node *ast.DotExpression, env *object.Environment) object.Object {
+	left := e.Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	switch leftT := left.(type) {
+	case *object.Hash:
+		return evalHashIndexExpression(left, &object.String{Value: node.Name.Value})
+	case *object.Instance:
+		if field, ok := leftT.Fields[node.Name.Value]; ok {
+			return field
+		}
+		return newError("unknown field `%s` for %s", node.Name.Value, leftT.Struct.Name)
+	default:
+		return newError("unknown field `%s` for %s", node.Name.Value, left.Type())
+	}
+}
+
+// 求值调用表达式中的函数部分，x.method(args)中的x.method解析为绑定了接收者的方法
+func (e *Evaluator) evalCallee(callee ast.Expression, env *object.Environment) object.Object {
+	dot, ok := callee.(*ast.DotExpression)
+	if !ok {
+		return e.Eval(callee, env)
+	}
+
+	receiver := e.Eval(dot.Left, env)
+	if isError(receiver) {
+		return receiver
+	}
+	return e.lookupMethod(receiver, dot.Name.Value)
+}
+
+func (e *Evaluator) lookupMethod(receiver object.Object, name string) object.Object {
+	switch receiverT := receiver.(type) {
+	case *object.Hash:
+		if pair, ok := receiverT.Pairs[(&object.String{Value: name}).HashKey()]; ok {
+			return pair.Value
+		}
+	case *object.Instance:
+		if field, ok := receiverT.Fields[name]; ok {
+			return field
+		}
+	}
+
+	if m, ok := methods[receiver.Type()][name]; ok {
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			return m(e, receiver, args...)
+		}}
+	}
+
+	if builtin, ok := e.lookupBuiltin(name); ok {
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			return builtin.Fn(append([]object.Object{receiver}, args...)...)
+		}}
+	}
+
+	return newError("undefined method `%s` for %s", name, receiver.Type())
+}
@@ -0,0 +1,124 @@
+package evaluator
+
+import (
+	"testing"
+
+	"monkey/object"
+)
+
+func TestDotExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let h = {"name": "monkey", "pos": {"x": 3}}; h.name`, "monkey"},
+		{`let h = {"pos": {"x": 3}}; h.pos.x`, 3},
+		{`let h = {"name": "monkey"}; h.age`, nil},
+		{`[1, 2].name`, errorResult("unknown field `name` for ARRAY")},
+	}
+
+	testEvalResults(t, tests)
+}
+
+func TestMethodCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"a,b,c".split(",").len()`, 3},
+		{`"a,b,c".split(",")[1]`, "b"},
+		{`" Monkey ".trim().upper()`, "MONKEY"},
+		{`"monkey".contains("key")`, true},
+		{`[1, 2, 3].map(fn(x) { x * 2 }).reduce(fn(acc, x) { acc + x }, 0)`, 12},
+		{`[1, 2, 3, 4].filter(fn(x) { x > 2 }).len()`, 2},
+		{`[1, 2, 3].join("-")`, "1-2-3"},
+		{`[1, 2].push(3).last()`, 3},
+		{`[1, 2, 3].map(len)`, errorResult("argument to `len` not supported, got INTEGER")},
+		{`{"b": 2, "a": 1}.keys().join(",")`, "a,b"},
+		{`{"b": 2, "a": 1}.values().reduce(fn(acc, x) { acc + x }, 0)`, 3},
+		{`{"a": 1}.has("a")`, true},
+		// 哈希中的同名成员优先于方法
+		{`let h = {"keys": fn() { 42 }}; h.keys()`, 42},
+		{`let obj = {"add": fn(a, b) { a + b }}; obj.add(1, 2)`, 3},
+		{`let double = fn(x) { x * 2 }; 5.double()`, errorResult("undefined method `double` for INTEGER")},
+		{`"a".split()`, errorResult("wrong number of arguments. got=0, want=1")},
+	}
+
+	testEvalResults(t, tests)
+}
+
+func testEvalResults(t *testing.T, tests []struct {
+	input    string
+	expected interface{}
+}) {
+	t.Helper()
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. want=%q, got=%q", expected, str.Value)
+			}
+		case errorResult:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestMethodStackFrame(t *testing.T) {
+	evaluated := testEval(`let obj = {"fail": fn() { 1 + true }};
+obj.fail();`)
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	if len(errObj.Stack) != 1 {
+		t.Fatalf("wrong stack size. got=%d", len(errObj.Stack))
+	}
+	if frame := errObj.Stack[0]; frame.Function != "fail" || frame.Line != 2 || frame.Column != 5 {
+		t.Errorf("wrong stack frame. got=%+v", frame)
+	}
+}
+
+func TestMethodCallbackStackFrame(t *testing.T) {
+	evaluated := testEval(`let check = fn(x) { x + true };
+[1, 2].map(check);`)
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	expected := []object.StackFrame{
+		{Function: "map", Line: 2, Column: 8},
+		{Function: "check", Line: 2, Column: 8},
+	}
+	if len(errObj.Stack) != len(expected) {
+		t.Fatalf("wrong stack. got=%+v", errObj.Stack)
+	}
+	for i, frame := range expected {
+		if errObj.Stack[i] != frame {
+			t.Errorf("stack[%d] wrong. want=%+v, got=%+v", i, frame, errObj.Stack[i])
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+)
+
+/*
+模块
+import("path") 加载一个模块，模块是一段源代码，由宿主程序提供的ModuleLoader读取
+求值时返回模块顶层绑定组成的哈希表，宏展开时模块顶层定义的宏在import所在的代码块中可见，
+每个模块在同一个求值器中只加载一次
+*/
+
+// ModuleLoader 根据import的路径返回模块的源代码
+type ModuleLoader func(path string) (string, error)
+
+// SetModuleLoader 设置读取模块的方式，没有设置时import返回错误
+func (e *Evaluator) SetModuleLoader(loader ModuleLoader) {
+	e.loader = loader
+}
+
+type module struct {
+	macros  *object.Environment // 模块顶层定义的宏
+	program ast.Node            // 展开宏之后的程序
+	exports object.Object       // 模块顶层的绑定，第一次求值之后记录
+	running bool                // 正在求值，用于发现循环导入
+}
+
+// 加载并展开模块，结果按路径缓存
+func (e *Evaluator) loadModule(path string) (*module, *object.Error) {
+	if m, ok := e.modules[path]; ok {
+		if m == nil {
+			return nil, newError("import cycle: `%s` is already being imported", path)
+		}
+		return m, nil
+	}
+	if e.loader == nil {
+		return nil, newError("cannot import `%s`: no module loader", path)
+	}
+
+	src, err := e.loader(path)
+	if err != nil {
+		return nil, newError("cannot import `%s`: %s", path, err)
+	}
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, newError("cannot import `%s`: %s", path, p.Errors()[0])
+	}
+
+	if e.modules == nil {
+		e.modules = make(map[string]*module)
+	}
+	// 加载过程中的模块记为nil
+	e.modules[path] = nil
+
+	// 模块的宏在模块自己的宏环境中展开，不影响导入处的状态
+	macroEnv, origins := e.macroEnv, e.origins
+	m := &module{macros: object.NewEnvironment()}
+	e.macroEnv, e.origins = m.macros, nil
+	budget := maxMacroExpansions
+	expanded, diagnostics := e.expandMacros(DefineMacros(program, m.macros), m.macros, 0, &budget)
+	e.macroEnv, e.origins = macroEnv, origins
+
+	if len(diagnostics) != 0 {
+		delete(e.modules, path)
+		return nil, newError("in module `%s`: %s", path, diagnostics[0].String())
+	}
+	m.program = expanded
+	e.modules[path] = m
+	return m, nil
+}
+
+// import(path) 对模块求值，返回顶层绑定组成的哈希表
+func (e *Evaluator) importModule(args ...object.Object) object.Object {
+	if err := CheckArgs("import", args, object.STRING_OBJ); err != nil {
+		return err
+	}
+	path := args[0].(*object.String).Value
+
+	m, err := e.loadModule(path)
+	if err != nil {
+		return err
+	}
+	if m.exports != nil {
+		return m.exports
+	}
+	if m.running {
+		return newError("import cycle: `%s` is already being imported", path)
+	}
+
+	m.running = true
+	defer func() { m.running = false }()
+
+	env := object.NewEnvironment()
+	if result := e.Eval(m.program, env); isError(result) {
+		return result
+	}
+	exports := make(map[string]object.Object)
+	for _, name := range env.Names() {
+		exports[name], _ = env.Get(name)
+	}
+	m.exports = newHash(exports)
+	return m.exports
+}
+
+// 语句中import的路径，import("path")或let name = import("path")，路径必须是字符串字面量
+func importPath(stmt ast.Statement) (*ast.CallExpression, string, bool) {
+	var exp ast.Expression
+	switch stmtT := stmt.(type) {
+	case *ast.ExpressionStatement:
+		exp = stmtT.Expression
+	case *ast.LetStatement:
+		exp = stmtT.Value
+	}
+
+	call, ok := exp.(*ast.CallExpression)
+	if !ok || len(call.Arguments) != 1 {
+		return nil, "", false
+	}
+	if ident, ok := call.Function.(*ast.Identifier); !ok || ident.Value != "import" {
+		return nil, "", false
+	}
+	path, ok := call.Arguments[0].(*ast.StringLiteral)
+	if !ok {
+		return nil, "", false
+	}
+	return call, path.Value, true
+}
+
+func hasImport(statements []ast.Statement) bool {
+	for _, statement := range statements {
+		if _, _, ok := importPath(statement); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// 把statements中导入的模块的宏放入env，加载失败时返回对应的诊断信息
+func (e *Evaluator) importMacros(statements []ast.Statement, env *object.Environment) []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, statement := range statements {
+		call, path, ok := importPath(statement)
+		if !ok {
+			continue
+		}
+		m, err := e.loadModule(path)
+		if err != nil {
+			pos := call.Function.(*ast.Identifier).Token
+			diagnostics = append(diagnostics, Diagnostic{Message: err.Message, Line: pos.Line, Column: pos.Column})
+			continue
+		}
+		for _, name := range m.macros.Names() {
+			macro, _ := m.macros.Get(name)
+			env.Set(name, macro)
+		}
+	}
+	return diagnostics
+}
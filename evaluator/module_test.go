@@ -0,0 +1,138 @@
+package evaluator
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"monkey/object"
+)
+
+const testLibrary = `
+let unless = macro(c, a, b) { quote(if (!(unquote(c))) { unquote(a) } else { unquote(b) }) };
+let double = fn(x) { x * 2 };
+`
+
+// 从modules中读取模块，loads记录每个模块被读取的次数
+func testModuleEvaluator(modules map[string]string, loads map[string]int) *Evaluator {
+	e := New()
+	e.SetModuleLoader(func(path string) (string, error) {
+		src, ok := modules[path]
+		if !ok {
+			return "", fmt.Errorf("module not found")
+		}
+		if loads != nil {
+			loads[path]++
+		}
+		return src, nil
+	})
+	return e
+}
+
+func testImport(e *Evaluator, input string) (object.Object, []Diagnostic) {
+	program := testParseProgram(input)
+	env := object.NewEnvironment()
+	macroEnv := object.NewEnclosedEnvironment(env)
+	program = DefineMacros(program, macroEnv)
+	expanded, diagnostics := e.ExpandMacros(program, macroEnv)
+	if len(diagnostics) != 0 {
+		return nil, diagnostics
+	}
+	return e.Eval(expanded, env), nil
+}
+
+func TestImportMacros(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		// 模块顶层定义的宏和绑定都被导出
+		{`let lib = import("lib"); unless(false, lib.double(2), 0)`, 4},
+		{`import("lib"); unless(true, 1, 2)`, 2},
+		// 代码块中导入的宏只在该代码块内可见
+		{`let f = fn() { import("lib"); unless(false, 1, 2) }; f()`, 1},
+		// 代码块中的定义遮蔽导入的同名宏
+		{`import("lib"); let f = fn() { let unless = macro(c, a, b) { quote(0) }; unless(false, 1, 2) }; f() + unless(false, 3, 4)`, 3},
+		// 模块中可以使用它导入的模块的宏
+		{`import("wrap"); twice(unless(false, 5, 0))`, 10},
+	}
+
+	modules := map[string]string{
+		"lib":  testLibrary,
+		"wrap": `import("lib"); let twice = macro(x) { quote(unless(false, unquote(x) * 2, 0)) };`,
+	}
+	for _, tt := range tests {
+		result, diagnostics := testImport(testModuleEvaluator(modules, nil), tt.input)
+		if len(diagnostics) != 0 {
+			t.Fatalf("unexpected diagnostics for %q: %v", tt.input, diagnostics)
+		}
+		testIntegerObject(t, result, tt.expected)
+	}
+}
+
+func TestImportScope(t *testing.T) {
+	e := testModuleEvaluator(map[string]string{"lib": testLibrary}, nil)
+	result, diagnostics := testImport(e, `let f = fn() { import("lib"); 1 }; unless(false, 1, 2)`)
+	if len(diagnostics) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", diagnostics)
+	}
+	errObj, ok := result.(*object.Error)
+	if !ok || errObj.Message != "identifier not found: unless" {
+		t.Errorf("macro should not be visible outside the block. got=%+v", result)
+	}
+}
+
+func TestImportOnce(t *testing.T) {
+	loads := make(map[string]int)
+	modules := map[string]string{"counter": `let items = [1, 2]; let count = len(items);`}
+	e := testModuleEvaluator(modules, loads)
+
+	result, diagnostics := testImport(e, `let a = import("counter"); let b = import("counter"); a.count + b.count`)
+	if len(diagnostics) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", diagnostics)
+	}
+	testIntegerObject(t, result, 4)
+
+	// 之后的程序使用同一个求值器时复用已经加载的模块
+	result, _ = testImport(e, `import("counter").count`)
+	testIntegerObject(t, result, 2)
+	if loads["counter"] != 1 {
+		t.Errorf("module should be loaded once. got=%d", loads["counter"])
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	modules := map[string]string{
+		"a":      `import("b");`,
+		"b":      `import("a");`,
+		"broken": `let = 1;`,
+		"bad":    `let m = macro(x) { quote(unquote(x)) }; m();`,
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import("missing")`, "line 1, column 1: cannot import `missing`: module not found"},
+		{`import("broken")`, "cannot import `broken`: expected next token to be IDENT"},
+		{`import("a")`, "import cycle: `a` is already being imported"},
+		{`let f = fn() { import("bad") }`, "in module `bad`: line 1, column 41: wrong number of arguments to macro `m`"},
+	}
+
+	for _, tt := range tests {
+		_, diagnostics := testImport(testModuleEvaluator(modules, nil), tt.input)
+		if len(diagnostics) != 1 || !strings.Contains(diagnostics[0].String(), tt.expected) {
+			t.Errorf("wrong diagnostics for %q. want=%q, got=%v", tt.input, tt.expected, diagnostics)
+		}
+	}
+
+	// 路径不是字面量时只在求值时加载
+	result, _ := testImport(testModuleEvaluator(modules, nil), `let path = "missing"; import(path)`)
+	if errObj, ok := result.(*object.Error); !ok || errObj.Message != "cannot import `missing`: module not found" {
+		t.Errorf("wrong error. got=%+v", result)
+	}
+	result, _ = testImport(New(), `let path = "lib"; import(path)`)
+	if errObj, ok := result.(*object.Error); !ok || errObj.Message != "cannot import `lib`: no module loader" {
+		t.Errorf("wrong error. got=%+v", result)
+	}
+}
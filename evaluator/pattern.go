@@ -0,0 +1,191 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+)
+
+// 将值与模式进行匹配，匹配成功时把绑定写入bindings
+// 匹配失败时返回描述形状不符的错误对象
+func (e *Evaluator) matchPattern(pattern ast.Expression, val object.Object, env *object.Environment, bindings map[string]object.Object) *object.Error {
+	switch patternT := pattern.(type) {
+	case *ast.Identifier:
+		// _ 为通配符，匹配任何值但不绑定
+		if patternT.Value != "_" {
+			bindings[patternT.Value] = val
+		}
+		return nil
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean, *ast.NullLiteral, *ast.PrefixExpression:
+		expected := e.Eval(pattern, env)
+		if errObj, ok := expected.(*object.Error); ok {
+			return errObj
+		}
+		if !objectsEqual(expected, val) {
+			return newError("pattern mismatch: want=%s, got=%s", expected.Inspect(), val.Inspect())
+		}
+		return nil
+	case *ast.ArrayPattern:
+		return e.matchArrayPattern(patternT, val, env, bindings)
+	case *ast.HashPattern:
+		return e.matchHashPattern(patternT, val, env, bindings)
+	default:
+		return newError("unsupported pattern: %s", pattern.String())
+	}
+}
+
+func (e *Evaluator) matchArrayPattern(pattern *ast.ArrayPattern, val object.Object, env *object.Environment, bindings map[string]object.Object) *object.Error {
+	arr, ok := val.(*object.Array)
+	if !ok {
+		return newError("cannot destructure %s as ARRAY", val.Type())
+	}
+
+	want := len(pattern.Elements)
+	got := len(arr.Elements)
+	if got < want {
+		return newError("not enough values to destructure: want=%d, got=%d", want, got)
+	}
+	if got > want && pattern.Rest == nil {
+		return newError("too many values to destructure: want=%d, got=%d", want, got)
+	}
+
+	for i, element := range pattern.Elements {
+		if err := e.matchPattern(element, arr.Elements[i], env, bindings); err != nil {
+			return err
+		}
+	}
+
+	if pattern.Rest != nil {
+		rest := make([]object.Object, got-want)
+		copy(rest, arr.Elements[want:])
+		bindings[pattern.Rest.Value] = &object.Array{Elements: rest}
+	}
+	return nil
+}
+
+func (e *Evaluator) matchHashPattern(pattern *ast.HashPattern, val object.Object, env *object.Environment, bindings map[string]object.Object) *object.Error {
+	hash, ok := val.(*object.Hash)
+	if !ok {
+		return newError("cannot destructure %s as HASH", val.Type())
+	}
+
+	for i, keyNode := range pattern.Keys {
+		key := e.Eval(keyNode, env)
+		if errObj, ok := key.(*object.Error); ok {
+			return errObj
+		}
+
+		hashKey, ok := key.(object.Hashtable)
+		if !ok {
+			return newError("unusable as hash key: %s", key.Type())
+		}
+
+		pair, ok := hash.Pairs[hashKey.HashKey()]
+		if !ok {
+			return newError("key not found in hash: %s", key.Inspect())
+		}
+
+		if err := e.matchPattern(pattern.Values[i], pair.Value, env, bindings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 解构let语句，全部匹配成功后才写入环境
+func (e *Evaluator) destructure(ls *ast.LetStatement, val object.Object, env *object.Environment) object.Object {
+	bindings := make(map[string]object.Object)
+	if err := e.matchPattern(ls.Pattern, val, env, bindings); err != nil {
+		return err
+	}
+
+	if err := e.declare(ls.Token, ls.Constant(), bindings, env); err != nil {
+		return err
+	}
+	return nil
+}
+
+// 字面量模式以及结构体字段的比较，只比较类型和值，数组和哈希逐个元素比较
+func objectsEqual(a, b object.Object) bool {
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch aT := a.(type) {
+	case *object.Integer:
+		return aT.Value == b.(*object.Integer).Value
+	case *object.String:
+		return aT.Value == b.(*object.String).Value
+	case *object.Instance:
+		return instancesEqual(aT, b.(*object.Instance))
+	case *object.Array:
+		bT := b.(*object.Array)
+		if len(aT.Elements) != len(bT.Elements) {
+			return false
+		}
+		for i, el := range aT.Elements {
+			if !objectsEqual(el, bT.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *object.Hash:
+		bT := b.(*object.Hash)
+		if len(aT.Pairs) != len(bT.Pairs) {
+			return false
+		}
+		for key, pair := range aT.Pairs {
+			other, ok := bT.Pairs[key]
+			if !ok || !objectsEqual(pair.Value, other.Value) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+func (e *Evaluator) evalMatchExpression(me *ast.MatchExpression, env *object.Environment) object.Object {
+	body, armEnv, err := e.selectMatchArm(me, env)
+	if err != nil {
+		return err
+	}
+	return e.Eval(body, armEnv)
+}
+
+// 依次尝试每个分支，返回第一个匹配的分支以及包含其绑定的环境
+func (e *Evaluator) selectMatchArm(me *ast.MatchExpression, env *object.Environment) (ast.Expression, *object.Environment, object.Object) {
+	subject := e.Eval(me.Subject, env)
+	if isError(subject) {
+		return nil, nil, subject
+	}
+
+	for _, arm := range me.Arms {
+		bindings := make(map[string]object.Object)
+		if err := e.matchPattern(arm.Pattern, subject, env, bindings); err != nil {
+			// 超出限制等错误不能当作匹配失败
+			if err.Kind != object.RUNTIME_ERROR {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		armEnv := object.NewEnclosedEnvironment(env)
+		for name, val := range bindings {
+			armEnv.Set(name, val)
+		}
+
+		if arm.Guard != nil {
+			guard := e.Eval(arm.Guard, armEnv)
+			if isError(guard) {
+				return nil, nil, guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+		return arm.Body, armEnv, nil
+	}
+
+	return nil, nil, newError("no match for value: %s", subject.Inspect())
+}
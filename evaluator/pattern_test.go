@@ -0,0 +1,94 @@
+package evaluator
+
+import (
+	"testing"
+
+	"monkey/object"
+)
+
+func TestDestructuringLet(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let [a, b] = [1, 2]; a * 10 + b;", 12},
+		{"let [a, ...rest] = [1, 2, 3]; len(rest) * 10 + a;", 21},
+		{"let [a, ...rest] = [1]; len(rest);", 0},
+		{"let [a, [b, c]] = [1, [2, 3]]; a + b + c;", 6},
+		{`let {name, age} = {"name": "monkey", "age": 5}; age;`, 5},
+		{`let {"age": years} = {"name": "monkey", "age": 5}; years;`, 5},
+		{`let {pos: [x, y]} = {"pos": [3, 4]}; x * y;`, 12},
+		{`let {1: one, true: yes} = {1: 10, true: 20}; one + yes;`, 30},
+		{"let [a, b] = [1];", "not enough values to destructure: want=2, got=1"},
+		{"let [a] = [1, 2];", "too many values to destructure: want=1, got=2"},
+		{"let [a, b] = 5;", "cannot destructure INTEGER as ARRAY"},
+		{`let {name} = [1];`, "cannot destructure ARRAY as HASH"},
+		{`let {name} = {"age": 1};`, "key not found in hash: name"},
+		// 匹配失败时不写入任何绑定
+		{"let [a, [b]] = [1, 2]; a;", "cannot destructure INTEGER as ARRAY"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+
+	env := object.NewEnvironment()
+	New().Eval(testParseProgram("let [a, [b]] = [1, 2];"), env)
+	if _, ok := env.Get("a"); ok {
+		t.Errorf("a should not be bound after a failed match")
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`match (1) { 1 => 10, _ => 20 }`, 10},
+		{`match (2) { 1 => 10, _ => 20 }`, 20},
+		{`match (-1) { -1 => 5, _ => 0 }`, 5},
+		{`match ("b") { "a" => 1, "b" => 2 }`, 2},
+		{`match (true) { false => 1, true => 2 }`, 2},
+		{`match ([1].rest().first()) { null => 1, _ => 2 }`, 1},
+		{`match ([1, 2, 3]) { [a] => a, [a, ...rest] => a + len(rest) }`, 3},
+		{`match ([1, [2, 3]]) { [1, [x, 3]] => x, _ => 0 }`, 2},
+		{`match ({"kind": "add", "n": 4}) { {kind: "sub", n} => 0 - n, {kind: "add", n} => n }`, 4},
+		{`match (5) { x if x > 10 => 1, x if x > 3 => 2, _ => 3 }`, 2},
+		{`let x = 1; match (7) { _ => x }`, 1},
+		{`let fact = fn(n, acc) { match (n) { 0 => acc, _ => fact(n - 1, acc * n) } }; fact(5, 1);`, 120},
+		{`match (3) { 1 => 1, 2 => 2 }`, "no match for value: 3"},
+		{`match (1) { x if y => 1 }`, "identifier not found: y"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
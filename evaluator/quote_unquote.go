@@ -8,29 +8,63 @@ import (
 	"monkey/token"
 )
 
-func quote(node ast.Node, env *object.Environment) object.Object {
-	node = evalUnquoteCalls(node, env)
+func (e *Evaluator) quote(call *ast.CallExpression, env *object.Environment) object.Object {
+	if len(call.Arguments) != 1 {
+		return newError("wrong number of arguments to `quote`: want=1, got=%d", len(call.Arguments))
+	}
+
+	// 在拷贝上替换unquote，宏体或函数体可以被多次求值
+	node, err := e.evalUnquoteCalls(ast.Clone(call.Arguments[0]), env)
+	if err != nil {
+		return err
+	}
 	return &object.Quote{Node: node}
 }
 
 // 对unquote的内容进行解析
-func evalUnquoteCalls(quote ast.Node, env *object.Environment) ast.Node {
-	return ast.Modify(quote, func(node ast.Node) ast.Node {
-		if !isUnquoteCall(node) {
+// unquote(x) 替换为x的值转换成的节点
+// unquote_splice(arr) 将数组的每个元素转换成节点，展开到参数列表、数组字面量或代码块中
+func (e *Evaluator) evalUnquoteCalls(quote ast.Node, env *object.Environment) (ast.Node, *object.Error) {
+	var err *object.Error
+
+	modified := ast.Modify(quote, func(node ast.Node) ast.Node {
+		if err != nil {
 			return node
 		}
 
-		call, ok := node.(*ast.CallExpression)
-		if !ok {
-			return node
+		switch nodeT := node.(type) {
+		case *ast.CallExpression:
+			if isUnquoteCall(nodeT) {
+				var replaced ast.Node
+				replaced, err = e.evalUnquote(nodeT, env)
+				if err != nil {
+					return node
+				}
+				return replaced
+			}
+			nodeT.Arguments, err = e.spliceExpressions(nodeT.Arguments, env)
+		case *ast.ArrayLiteral:
+			nodeT.Elements, err = e.spliceExpressions(nodeT.Elements, env)
+		case *ast.BlockStatement:
+			nodeT.Statements, err = e.spliceStatements(nodeT.Statements, env)
 		}
+		return node
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		if len(call.Arguments) != 1 {
-			return node
+	// 其他位置的unquote_splice无法展开
+	ast.Inspect(modified, func(node ast.Node) bool {
+		if call, ok := node.(*ast.CallExpression); ok && isUnquoteSpliceCall(call) && err == nil {
+			err = newError("unquote_splice is only allowed in argument lists, array literals and blocks")
 		}
-		unquoted := Eval(call.Arguments[0], env)
-		return convertObjectToASTNode(unquoted)
+		return err == nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	return modified, nil
 }
 
 func isUnquoteCall(node ast.Node) bool {
@@ -42,14 +76,130 @@ func isUnquoteCall(node ast.Node) bool {
 	return callExpression.Function.TokenLiteral() == "unquote"
 }
 
-func convertObjectToASTNode(obj object.Object) ast.Node {
+func isUnquoteSpliceCall(node ast.Node) bool {
+	callExpression, ok := node.(*ast.CallExpression)
+	if !ok {
+		return false
+	}
+
+	return callExpression.Function.TokenLiteral() == "unquote_splice"
+}
+
+func (e *Evaluator) evalUnquote(call *ast.CallExpression, env *object.Environment) (ast.Node, *object.Error) {
+	if len(call.Arguments) != 1 {
+		return nil, newError("wrong number of arguments to `unquote`: want=1, got=%d", len(call.Arguments))
+	}
+
+	unquoted := e.Eval(call.Arguments[0], env)
+	if errObj, ok := unquoted.(*object.Error); ok {
+		return nil, errObj
+	}
+	return e.convertObjectToASTNode(unquoted)
+}
+
+// 对unquote_splice的参数求值，返回展开后的节点
+func (e *Evaluator) evalUnquoteSplice(call *ast.CallExpression, env *object.Environment) ([]ast.Node, *object.Error) {
+	if len(call.Arguments) != 1 {
+		return nil, newError("wrong number of arguments to `unquote_splice`: want=1, got=%d", len(call.Arguments))
+	}
+
+	spliced := e.Eval(call.Arguments[0], env)
+	if errObj, ok := spliced.(*object.Error); ok {
+		return nil, errObj
+	}
+	arr, ok := spliced.(*object.Array)
+	if !ok {
+		return nil, newError("argument to `unquote_splice` must be ARRAY, got %s", spliced.Type())
+	}
+
+	nodes := make([]ast.Node, 0, len(arr.Elements))
+	for _, el := range arr.Elements {
+		node, err := e.convertObjectToASTNode(el)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+func (e *Evaluator) spliceExpressions(exps []ast.Expression, env *object.Environment) ([]ast.Expression, *object.Error) {
+	var result []ast.Expression
+
+	for i, exp := range exps {
+		if !isUnquoteSpliceCall(exp) {
+			if result != nil {
+				result = append(result, exp)
+			}
+			continue
+		}
+
+		if result == nil {
+			result = append([]ast.Expression{}, exps[:i]...)
+		}
+		nodes, err := e.evalUnquoteSplice(exp.(*ast.CallExpression), env)
+		if err != nil {
+			return exps, err
+		}
+		for _, node := range nodes {
+			spliced, ok := node.(ast.Expression)
+			if !ok {
+				return exps, newError("cannot splice %s into an expression list", node.String())
+			}
+			result = append(result, spliced)
+		}
+	}
+
+	if result == nil {
+		return exps, nil
+	}
+	return result, nil
+}
+
+func (e *Evaluator) spliceStatements(stmts []ast.Statement, env *object.Environment) ([]ast.Statement, *object.Error) {
+	var result []ast.Statement
+
+	for i, stmt := range stmts {
+		exprStmt, ok := stmt.(*ast.ExpressionStatement)
+		if !ok || !isUnquoteSpliceCall(exprStmt.Expression) {
+			if result != nil {
+				result = append(result, stmt)
+			}
+			continue
+		}
+
+		if result == nil {
+			result = append([]ast.Statement{}, stmts[:i]...)
+		}
+		nodes, err := e.evalUnquoteSplice(exprStmt.Expression.(*ast.CallExpression), env)
+		if err != nil {
+			return stmts, err
+		}
+		for _, node := range nodes {
+			switch nodeT := node.(type) {
+			case ast.Statement:
+				result = append(result, nodeT)
+			case ast.Expression:
+				result = append(result, &ast.ExpressionStatement{Token: exprStmt.Token, Expression: nodeT})
+			}
+		}
+	}
+
+	if result == nil {
+		return stmts, nil
+	}
+	return result, nil
+}
+
+// 将对象转换为能求值得到该对象的AST节点
+func (e *Evaluator) convertObjectToASTNode(obj object.Object) (ast.Node, *object.Error) {
 	switch objT := obj.(type) {
 	case *object.Integer:
 		t := token.Token{
 			Type:    token.INT,
 			Literal: fmt.Sprintf("%d", objT.Value),
 		}
-		return &ast.IntegerLiteral{Token: t, Value: objT.Value}
+		return &ast.IntegerLiteral{Token: t, Value: objT.Value}, nil
 	case *object.Boolean:
 		var t token.Token
 		if objT.Value {
@@ -57,10 +207,186 @@ func convertObjectToASTNode(obj object.Object) ast.Node {
 		} else {
 			t = token.Token{Type: token.FALSE, Literal: "false"}
 		}
-		return &ast.Boolean{Token: t, Value: objT.Value}
+		return &ast.Boolean{Token: t, Value: objT.Value}, nil
+	case *object.Null:
+		return &ast.NullLiteral{Token: token.Token{Type: token.NULL, Literal: "null"}}, nil
+	case *object.String:
+		t := token.Token{Type: token.STRING, Literal: objT.Value}
+		return &ast.StringLiteral{Token: t, Value: objT.Value}, nil
+	case *object.Array:
+		elements, err := e.convertObjectsToExpressions(objT.Elements)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.ArrayLiteral{Token: token.Token{Type: token.LBRACKET, Literal: "["}, Elements: elements}, nil
+	case *object.Hash:
+		hash := &ast.HashLiteral{Token: token.Token{Type: token.LBRACE, Literal: "{"}, Pairs: []*ast.HashPair{}}
+		for _, pair := range sortedPairs(objT) {
+			kv, err := e.convertObjectsToExpressions([]object.Object{pair.Key, pair.Value})
+			if err != nil {
+				return nil, err
+			}
+			hash.Pairs = append(hash.Pairs, &ast.HashPair{Key: kv[0], Value: kv[1]})
+		}
+		return hash, nil
+	case *object.Function:
+		fn := &ast.FunctionLiteral{
+			Token:      token.Token{Type: token.FUNCTION, Literal: "fn"},
+			Parameters: objT.Parameters,
+			Defaults:   objT.Defaults,
+			Rest:       objT.Rest,
+			Body:       objT.Body,
+		}
+		// 闭包捕获的环境无法转换，插入到其他位置后自由变量会引用插入处的同名变量
+		for _, name := range freeVariables(fn) {
+			if _, ok := objT.Env.Get(name); ok {
+				return nil, newError("cannot convert closure capturing `%s` to AST node", name)
+			}
+		}
+		// 拷贝函数体，展开结果被修改时不影响函数本身
+		return ast.Clone(fn), nil
+	case *object.Builtin:
+		if name, ok := e.builtinName(objT); ok {
+			return newIdentifier(name), nil
+		}
+		return nil, newError("cannot convert anonymous builtin function to AST node")
+	case *object.StructType:
+		return newIdentifier(objT.Name), nil
+	case *object.Instance:
+		// 转换为构造函数调用 Point(1, 2)
+		fields := make([]object.Object, len(objT.Struct.Fields))
+		for i, name := range objT.Struct.Fields {
+			fields[i] = objT.Fields[name]
+		}
+		args, err := e.convertObjectsToExpressions(fields)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.CallExpression{
+			Token:     token.Token{Type: token.LPAREN, Literal: "("},
+			Function:  newIdentifier(objT.Struct.Name),
+			Arguments: args,
+		}, nil
+	case *object.Macro:
+		return ast.Clone(&ast.MacroLiteral{
+			Token:      token.Token{Type: token.MACRO, Literal: "macro"},
+			Parameters: objT.Parameters,
+			Body:       objT.Body,
+		}), nil
 	case *object.Quote:
-		return objT.Node
+		// 同一个quote可能被插入多次，每次插入一份拷贝
+		return e.cloneQuoted(objT.Node), nil
 	default:
-		return nil
+		return nil, newError("cannot convert %s to AST node", obj.Type())
+	}
+}
+
+// 拷贝quote中的节点，宏展开期间记录每个拷贝对应的原节点，
+// 卫生处理据此识别来自宏调用参数的代码
+func (e *Evaluator) cloneQuoted(node ast.Node) ast.Node {
+	cloned := ast.Clone(node)
+	if e.origins == nil {
+		return cloned
+	}
+
+	originals := inspectedNodes(node)
+	for i, copied := range inspectedNodes(cloned) {
+		original := originals[i]
+		if root, ok := e.origins[original]; ok {
+			original = root
+		}
+		e.origins[copied] = original
+	}
+	return cloned
+}
+
+// 按ast.Inspect的顺序列出所有节点，拷贝与原节点的顺序一一对应
+func inspectedNodes(node ast.Node) []ast.Node {
+	var nodes []ast.Node
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n != nil {
+			nodes = append(nodes, n)
+		}
+		return true
+	})
+	return nodes
+}
+
+// 函数中引用了但没有在函数内绑定的变量名，按出现的顺序
+// 函数体内任何位置(包括catch和match分支)绑定的名称都视为函数的局部变量
+func freeVariables(fn *ast.FunctionLiteral) []string {
+	var free []string
+	seen := make(map[string]bool)
+
+	var visit func(fn *ast.FunctionLiteral, outer map[string]bool)
+	visit = func(fn *ast.FunctionLiteral, outer map[string]bool) {
+		local := make(map[string]bool, len(outer))
+		for name := range outer {
+			local[name] = true
+		}
+		// 绑定以及字段名等不是变量引用的标识符
+		skip := make(map[*ast.Identifier]bool)
+		ast.Inspect(fn, func(node ast.Node) bool {
+			if inner, ok := node.(*ast.FunctionLiteral); ok && inner != fn {
+				return false
+			}
+			for _, binder := range binders(node) {
+				local[binder.Value] = true
+				skip[binder] = true
+			}
+			for _, ident := range nonVariables(node) {
+				skip[ident] = true
+			}
+			return node != nil
+		})
+
+		ast.Inspect(fn, func(node ast.Node) bool {
+			switch nodeT := node.(type) {
+			case *ast.FunctionLiteral:
+				if nodeT != fn {
+					visit(nodeT, local)
+					return false
+				}
+			case *ast.Identifier:
+				if !skip[nodeT] && !local[nodeT.Value] && !seen[nodeT.Value] {
+					seen[nodeT.Value] = true
+					free = append(free, nodeT.Value)
+				}
+			}
+			return node != nil
+		})
+	}
+	visit(fn, nil)
+	return free
+}
+
+func (e *Evaluator) convertObjectsToExpressions(objs []object.Object) ([]ast.Expression, *object.Error) {
+	exps := make([]ast.Expression, 0, len(objs))
+	for _, obj := range objs {
+		node, err := e.convertObjectToASTNode(obj)
+		if err != nil {
+			return nil, err
+		}
+		exp, ok := node.(ast.Expression)
+		if !ok {
+			return nil, newError("cannot use %s as an expression", node.String())
+		}
+		exps = append(exps, exp)
+	}
+	return exps, nil
+}
+
+// 查找内置函数注册时的名称
+func (e *Evaluator) builtinName(builtin *object.Builtin) (string, bool) {
+	for name, b := range e.builtins {
+		if b == builtin {
+			return name, true
+		}
+	}
+	for name, b := range builtins {
+		if b == builtin {
+			return name, true
+		}
 	}
+	return "", false
 }
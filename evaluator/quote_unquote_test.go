@@ -3,6 +3,7 @@ package evaluator
 import (
 	"testing"
 
+	"monkey/ast"
 	"monkey/object"
 )
 
@@ -49,6 +50,27 @@ func TestQuoteUnquote(t *testing.T) {
 		{`quote(unquote(true == false))`, `false`},
 		{`quote(unquote(quote(4 + 4)))`, `(4 + 4)`},
 		{`let quotedInfixExpression = quote(4 + 4); quote(unquote(4 + 4) + unquote(quotedInfixExpression))`, `(8 + (4 + 4))`},
+		{`quote(f(unquote(1 + 1), x: unquote(2 + 2)))`, `f(2, x: 4)`},
+		{`quote({unquote(1 + 1): unquote(2 + 2)})`, `{2:4}`},
+		{`quote(fn(a = unquote(1 + 1)) { a })`, `fn(a = 2)a`},
+		{`quote(match (x) { 1 if unquote(1 < 2) => unquote(2 + 2) })`, `match (x) { 1 if true => 4 }`},
+		{`quote(unquote("a" + "b"))`, `ab`},
+		{`quote(unquote([1, "two", [true]]))`, `[1, two, [true]]`},
+		{`quote(unquote({"b": 2, "a": [1]}))`, `{a:[1], b:2}`},
+		{`quote(unquote(puts("")))`, `null`},
+		{`quote(unquote(fn(x) { x + 1 }))`, `fn(x)(x + 1)`},
+		{`quote(unquote(len))`, `len`},
+		{`struct Point { x, y }; quote(unquote(Point(1, -2)))`, `Point(1, -2)`},
+		{`let args = [quote(a), 2]; quote(f(1, unquote_splice(args), 3))`, `f(1, a, 2, 3)`},
+		{`quote([unquote_splice([1, 2]), unquote_splice([])])`, `[1, 2]`},
+		{`let stmts = [quote(puts(1)), quote(2)]; quote(fn() { unquote_splice(stmts); 3 })`, `fn()puts(1)23`},
+		// 同一个quote多次求值时每次使用新的值
+		{`let q = fn(x) { quote(unquote(x) + 1) }; let first = q(1); q(2)`, `(2 + 1)`},
+		// 插入的函数是函数体的拷贝
+		{`let inc = fn(x) { x + 1 }; let q = quote(unquote(inc)); inc(1); q`, `fn(x)(x + 1)`},
+		// 没有自由变量或只引用内置函数的函数可以转换
+		{`let adder = fn(x) { fn(y) { x + y } }; quote(unquote(adder))`, `fn(x)fn(y)(x + y)`},
+		{`let size = fn(a) { len(a) }; quote(unquote(size))`, `fn(a)len(a)`},
 	}
 
 	for _, tt := range tests {
@@ -67,3 +89,60 @@ func TestQuoteUnquote(t *testing.T) {
 		}
 	}
 }
+
+func TestUnquoteErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quote()`, "wrong number of arguments to `quote`: want=1, got=0"},
+		{`quote(unquote(1, 2))`, "wrong number of arguments to `unquote`: want=1, got=2"},
+		{`quote(unquote(1 + true))`, "type mismatch: INTEGER + BOOLEAN"},
+		{`quote(unquote([1].map))`, "unknown field `map` for ARRAY"},
+		{`quote(1 + unquote_splice([1]))`, "unquote_splice is only allowed in argument lists, array literals and blocks"},
+		{`quote(f(unquote_splice(1)))`, "argument to `unquote_splice` must be ARRAY, got INTEGER"},
+		// 闭包捕获的变量无法转换
+		{`let base = 1; let add = fn(x) { x + base }; quote(unquote(add))`, "cannot convert closure capturing `base` to AST node"},
+		{`let adder = fn(x) { fn(y) { x + y } }; quote(unquote(adder(1)))`, "cannot convert closure capturing `x` to AST node"},
+		{`let f = fn(n) { f(n - 1) }; quote(unquote(f))`, "cannot convert closure capturing `f` to AST node"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("object is not Error for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+		}
+	}
+
+	// 宿主注册的匿名内置函数无法转换
+	e := New()
+	env := object.NewEnvironment()
+	env.Set("anon", &object.Builtin{Fn: func(args ...object.Object) object.Object { return NULL }})
+	evaluated := e.Eval(testParseProgram(`quote(unquote(anon))`), env)
+	if errObj, ok := evaluated.(*object.Error); !ok || errObj.Message != "cannot convert anonymous builtin function to AST node" {
+		t.Errorf("expected conversion error. got=%+v", evaluated)
+	}
+}
+
+func TestUnquoteCopiesQuote(t *testing.T) {
+	evaluated := testEval(`let q = quote(1 + 2); [q, quote(unquote(q) * unquote(q))]`)
+	elements := evaluated.(*object.Array).Elements
+	q := elements[0].(*object.Quote).Node
+	product, ok := elements[1].(*object.Quote).Node.(*ast.InfixExpression)
+	if !ok {
+		t.Fatalf("expected *ast.InfixExpression. got=%T", elements[1].(*object.Quote).Node)
+	}
+	if product.String() != "((1 + 2) * (1 + 2))" {
+		t.Errorf("wrong quote. got=%q", product.String())
+	}
+
+	// 每次插入的都是拷贝，不与原来的quote或者其他插入位置共享节点
+	if product.Left == product.Right || product.Left == q || product.Right == q {
+		t.Errorf("unquoted nodes should not be shared")
+	}
+}
@@ -0,0 +1,89 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+)
+
+/*
+结构体
+struct Point { x, y } 在当前作用域中绑定一个结构体类型，调用它创建实例
+Point(1, 2) 或 Point(x: 1, y: 2)，所有字段都必须提供
+*/
+
+func (e *Evaluator) evalStructStatement(ss *ast.StructStatement, env *object.Environment) object.Object {
+	fields := make([]string, len(ss.Fields))
+	for i, field := range ss.Fields {
+		fields[i] = field.Value
+	}
+
+	st := &object.StructType{Name: ss.Name.Value, Fields: fields}
+	if err := e.declare(ss.Token, false, map[string]object.Object{st.Name: st}, env); err != nil {
+		return err
+	}
+	return nil
+}
+
+// 构造实例，位置参数按字段声明的顺序绑定，命名参数按字段名绑定
+func newInstance(st *object.StructType, args []object.Object, named map[string]object.Object) object.Object {
+	if len(args) > len(st.Fields) {
+		return newError("wrong number of arguments to `%s`: want=%d, got=%d", st.Name, len(st.Fields), len(args))
+	}
+
+	fields := make(map[string]object.Object, len(st.Fields))
+	for i, arg := range args {
+		fields[st.Fields[i]] = arg
+	}
+
+	for name, val := range named {
+		if !hasField(st, name) {
+			return newError("unknown field `%s` for `%s`", name, st.Name)
+		}
+		if _, ok := fields[name]; ok {
+			return newError("multiple values for field `%s` of `%s`", name, st.Name)
+		}
+		fields[name] = val
+	}
+
+	for _, name := range st.Fields {
+		if _, ok := fields[name]; !ok {
+			return newError("missing field `%s` for `%s`", name, st.Name)
+		}
+	}
+	return &object.Instance{Struct: st, Fields: fields}
+}
+
+func hasField(st *object.StructType, name string) bool {
+	for _, field := range st.Fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// 同一结构体类型且所有字段相等的实例相等
+func instancesEqual(a, b *object.Instance) bool {
+	if a.Struct != b.Struct {
+		return false
+	}
+	for _, name := range a.Struct.Fields {
+		if !objectsEqual(a.Fields[name], b.Fields[name]) {
+			return false
+		}
+	}
+	return true
+}
+
+func evalInstanceInfixExpression(operator string, left, right object.Object) object.Object {
+	equal := instancesEqual(left.(*object.Instance), right.(*object.Instance))
+
+	switch operator {
+	case "==":
+		return nativeBoolToBooleanObject(equal)
+	case "!=":
+		return nativeBoolToBooleanObject(!equal)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
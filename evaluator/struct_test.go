@@ -0,0 +1,57 @@
+package evaluator
+
+import (
+	"testing"
+
+	"monkey/object"
+)
+
+func TestStructs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"struct Point { x, y }; let p = Point(1, 2); p.x * 10 + p.y;", 12},
+		{"struct Point { x, y }; Point(y: 2, x: 1).x;", 1},
+		{"struct Point { x, y }; Point(1, y: 2).y;", 2},
+		{"struct Point { x, y }; Point(1, 2) == Point(1, 2);", true},
+		{"struct Point { x, y }; Point(1, 2) != Point(2, 1);", true},
+		{"struct Line { from, to }; struct Point { x, y }; Line(Point(0, 0), Point(1, 1)) == Line(Point(0, 0), Point(1, 1));", true},
+		// 数组和哈希字段逐个元素比较
+		{"struct P { a }; P([1, [2]]) == P([1, [2]]);", true},
+		{"struct P { a }; P([1]) == P([2]);", false},
+		{`struct P { a }; P({"k": [1]}) == P({"k": [1]});`, true},
+		{`struct P { a }; P({"k": 1}) == P({"k": 2});`, false},
+		// 字段相同但类型不同的实例不相等
+		{"struct A { v }; struct B { v }; A(1) == B(1);", false},
+		{"struct Point { x, y }; type(Point(1, 2));", "Point"},
+		{"struct Point { x, y }; type(Point);", "STRUCT"},
+		{"type(1);", "INTEGER"},
+		{"struct Point { x, y }; Point(1, 2).z;", errorResult("unknown field `z` for Point")},
+		{"struct Point { x, y }; Point(1);", errorResult("missing field `y` for `Point`")},
+		{"struct Point { x, y }; Point(1, 2, 3);", errorResult("wrong number of arguments to `Point`: want=2, got=3")},
+		{"struct Point { x, y }; Point(1, 2, z: 3);", errorResult("unknown field `z` for `Point`")},
+		{"struct Point { x, y }; Point(1, 2, x: 3);", errorResult("multiple values for field `x` of `Point`")},
+		{"struct Point { x, y }; Point(1, 2) < Point(1, 2);", errorResult("unknown operator: INSTANCE < INSTANCE")},
+		{"const Point = 1; struct Point { x, y };", errorResult("cannot reassign constant `Point`")},
+		{`struct Shape { area }; let sq = Shape(fn() { 16 }); sq.area();`, 16},
+		{`struct Point { x, y }; match (Point(1, 2)) { 0 => 0, p => p.y }`, 2},
+	}
+
+	testEvalResults(t, tests)
+}
+
+func TestInstanceInspect(t *testing.T) {
+	evaluated := testEval(`struct Person { name, age }; Person("monkey", 5);`)
+
+	instance, ok := evaluated.(*object.Instance)
+	if !ok {
+		t.Fatalf("object is not Instance. got=%T (%+v)", evaluated, evaluated)
+	}
+	if instance.Inspect() != "Person{name: monkey, age: 5}" {
+		t.Errorf("wrong Inspect. got=%q", instance.Inspect())
+	}
+	if instance.Struct.Inspect() != "struct Person { name, age }" {
+		t.Errorf("wrong struct Inspect. got=%q", instance.Struct.Inspect())
+	}
+}
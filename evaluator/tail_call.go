@@ -0,0 +1,125 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+)
+
+/*
+尾调用优化
+函数体中处于尾位置的调用(return的值，或函数体最后一个表达式)不会立即执行，
+而是返回tailCall交给applyFunction循环执行，深度的尾递归因此不会增长Go的栈
+*/
+
+const TAIL_CALL_OBJ = "TAIL_CALL"
+
+// 只在求值器内部传递，不会作为结果暴露给Monkey代码
+type tailCall struct {
+	fn    object.Object
+	args  []object.Object
+	named map[string]object.Object
+	frame object.StackFrame // 替换调用栈中当前函数的帧
+}
+
+func (tc *tailCall) Type() object.ObjectType { return TAIL_CALL_OBJ }
+
+func (tc *tailCall) Inspect() string { return "tail call" }
+
+// 对函数体求值，tail表示该代码块的值是否就是函数的返回值
+// 无论tail是什么，return语句的值总是处于尾位置
+func (e *Evaluator) evalTailBlock(block *ast.BlockStatement, env *object.Environment, tail bool) object.Object {
+	var result object.Object
+
+	for i, statement := range block.Statements {
+		if err := e.checkContext(); err != nil {
+			return err
+		}
+		result = e.evalTailStatement(statement, env, tail && i == len(block.Statements)-1)
+
+		if result != nil {
+			rt := result.Type()
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+				return result
+			}
+		}
+	}
+	return result
+}
+
+func (e *Evaluator) evalTailStatement(stmt ast.Statement, env *object.Environment, tail bool) object.Object {
+	switch stmtT := stmt.(type) {
+	case *ast.ReturnStatement:
+		if err := e.step(); err != nil {
+			return err
+		}
+		val := e.evalTailExpression(stmtT.ReturnValue, env, true)
+		if isError(val) {
+			return val
+		}
+		return &object.ReturnValue{Value: val}
+	case *ast.ExpressionStatement:
+		if err := e.step(); err != nil {
+			return err
+		}
+		return e.evalTailExpression(stmtT.Expression, env, tail)
+	default:
+		return e.Eval(stmt, env)
+	}
+}
+
+func (e *Evaluator) evalTailExpression(exp ast.Expression, env *object.Environment, tail bool) object.Object {
+	switch expT := exp.(type) {
+	case *ast.IfExpression:
+		if err := e.step(); err != nil {
+			return err
+		}
+		condition := e.Eval(expT.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if isTruthy(condition) {
+			return e.evalTailBlock(expT.Consequence, env, tail)
+		} else if expT.Alternative != nil {
+			return e.evalTailBlock(expT.Alternative, env, tail)
+		} else {
+			return NULL
+		}
+	case *ast.MatchExpression:
+		if err := e.step(); err != nil {
+			return err
+		}
+		body, armEnv, err := e.selectMatchArm(expT, env)
+		if err != nil {
+			return err
+		}
+		return e.evalTailExpression(body, armEnv, tail)
+	case *ast.CallExpression:
+		if !tail || expT.Function.TokenLiteral() == "quote" {
+			return e.Eval(exp, env)
+		}
+		if err := e.step(); err != nil {
+			return err
+		}
+
+		function := e.evalCallee(expT.Function, env)
+		if isError(function) {
+			return function
+		}
+		args := e.evalExpressions(expT.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+		named, err := e.evalNamedArguments(expT.NamedArguments, env)
+		if err != nil {
+			return err
+		}
+
+		// 只有Monkey函数需要优化，内置函数直接调用
+		if _, ok := function.(*object.Function); !ok {
+			return e.callFunction(expT, function, args, named)
+		}
+		return &tailCall{fn: function, args: args, named: named, frame: e.newStackFrame(expT, function)}
+	default:
+		return e.Eval(exp, env)
+	}
+}
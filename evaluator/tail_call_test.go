@@ -0,0 +1,65 @@
+package evaluator
+
+import (
+	"testing"
+
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+)
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{`let count = fn(n, acc) { if (n == 0) { acc } else { count(n - 1, acc + 1) } };
+count(100000, 0);`, 100000},
+		{`let count = fn(n, acc) { if (n == 0) { return acc; } return count(n - 1, acc + 1); };
+count(100000, 0);`, 100000},
+		{`let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } };
+let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } };
+if (even(100000)) { 1 } else { 0 };`, 1},
+		// 非尾位置的if中的return同样是尾调用
+		{`let f = fn(n) { if (n > 0) { return f(n - 1); } 7; };
+f(100000);`, 7},
+		// 尾位置调用内置函数
+		{`let f = fn(arr) { len(arr) }; f([1, 2, 3]);`, 3},
+	}
+
+	for _, tt := range tests {
+		e := New()
+		// 尾递归不增加调用深度
+		e.SetLimits(Limits{MaxDepth: 10})
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		testIntegerObject(t, e.Eval(program, object.NewEnvironment()), tt.expected)
+	}
+}
+
+func TestNonTailCallsAreNotDeferred(t *testing.T) {
+	// 非尾位置的调用必须立即执行，保留其副作用
+	input := `let calls = fn(n) { if (n > 0) { calls(n - 1); n } else { 0 } };
+calls(3);`
+
+	testIntegerObject(t, testEval(input), 3)
+}
+
+func TestTailCallTraceback(t *testing.T) {
+	input := `let fail = fn() { x };
+let count = fn(n) { if (n == 0) { fail() } else { count(n - 1) } };
+count(3);`
+
+	errObj, ok := testEval(input).(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned")
+	}
+
+	// count(3)的帧依次被count(2)、count(1)、count(0)、fail()替换
+	expected := `Traceback (most recent call last):
+  ... 4 tail call(s) elided
+  line 2, column 35, in call to fail
+ERROR: identifier not found: x`
+	if errObj.Traceback() != expected {
+		t.Errorf("wrong traceback. want=%q, got=%q", expected, errObj.Traceback())
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"monkey/format"
+)
+
+// monkey fmt [-w] [-d] [file ...]
+// 没有文件时格式化标准输入，结果写到标准输出
+func runFmt(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	write := flags.Bool("w", false, "write result to (source) file instead of stdout")
+	diff := flags.Bool("d", false, "display diffs instead of rewriting files")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: monkey fmt [-w] [-d] [file ...]\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		if *write {
+			fmt.Fprintf(stderr, "monkey fmt: cannot use -w with standard input\n")
+			return 2
+		}
+		src, err := io.ReadAll(stdin)
+		if err == nil {
+			err = formatFile("<standard input>", src, false, *diff, stdout)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "<standard input>: %s\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		src, err := os.ReadFile(path)
+		if err == nil {
+			err = formatFile(path, src, *write, *diff, stdout)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", path, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+func formatFile(path string, src []byte, write, diff bool, stdout io.Writer) error {
+	formatted, err := format.Source(src)
+	if err != nil {
+		return err
+	}
+
+	if !write && !diff {
+		_, err = stdout.Write(formatted)
+		return err
+	}
+	if bytes.Equal(src, formatted) {
+		return nil
+	}
+
+	if write {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, formatted, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	if diff {
+		_, err = io.WriteString(stdout, unifiedDiff(path, src, formatted))
+	}
+	return err
+}
+
+// 差异中每一行的修改
+type edit struct {
+	op   byte // ' ' 相同，'-' 删除，'+' 添加
+	text string
+}
+
+// 差异中修改前后保留的相同的行数
+const diffContext = 3
+
+// 逐行比较a和b，生成统一格式的差异
+func unifiedDiff(path string, a, b []byte) string {
+	edits := diffLines(splitLines(string(a)), splitLines(string(b)))
+
+	// 每个修改之前在a和b中的行数
+	aPos := make([]int, len(edits)+1)
+	bPos := make([]int, len(edits)+1)
+	for i, e := range edits {
+		aPos[i+1], bPos[i+1] = aPos[i], bPos[i]
+		if e.op != '+' {
+			aPos[i+1]++
+		}
+		if e.op != '-' {
+			bPos[i+1]++
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s.orig\n+++ %s\n", path, path)
+
+	for i := 0; i < len(edits); {
+		for i < len(edits) && edits[i].op == ' ' {
+			i++
+		}
+		if i == len(edits) {
+			break
+		}
+
+		// 相邻修改之间的相同行不超过两倍上下文时合并为一段
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i + 1
+		for j := i; j < len(edits) && j-end < 2*diffContext; j++ {
+			if edits[j].op != ' ' {
+				end = j + 1
+			}
+		}
+		if end += diffContext; end > len(edits) {
+			end = len(edits)
+		}
+
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n",
+			hunkRange(aPos[start], aPos[end]-aPos[start]), hunkRange(bPos[start], bPos[end]-bPos[start]))
+		for _, e := range edits[start:end] {
+			out.WriteByte(e.op)
+			out.WriteString(e.text)
+			if !strings.HasSuffix(e.text, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return out.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// 基于最长公共子序列的逐行比较
+func diffLines(a, b []string) []edit {
+	// lcs[i][j]为a[i:]和b[j:]的最长公共子序列长度
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var edits []edit
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{'-', a[i]})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j]})
+			j++
+		}
+	}
+	return edits
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunFmt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.mk")
+	if err := os.WriteFile(path, []byte("let x=((1+2)*3)\nputs(x)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if status := runFmt([]string{"-d", path}, nil, &stdout, &stderr); status != 0 {
+		t.Fatalf("wrong status %d: %s", status, stderr.String())
+	}
+	expected := "--- " + path + ".orig\n+++ " + path + "\n" +
+		"@@ -1,2 +1,2 @@\n-let x=((1+2)*3)\n-puts(x)\n+let x = (1 + 2) * 3;\n+puts(x);\n"
+	if stdout.String() != expected {
+		t.Errorf("wrong diff.\nwant=%q\ngot=%q", expected, stdout.String())
+	}
+
+	stdout.Reset()
+	if status := runFmt([]string{"-w", path}, nil, &stdout, &stderr); status != 0 {
+		t.Fatalf("wrong status %d: %s", status, stderr.String())
+	}
+	written, _ := os.ReadFile(path)
+	if string(written) != "let x = (1 + 2) * 3;\nputs(x);\n" || stdout.Len() != 0 {
+		t.Errorf("wrong result. file=%q, stdout=%q", written, stdout.String())
+	}
+
+	// 已经格式化的文件没有差异
+	if status := runFmt([]string{"-d", path}, nil, &stdout, &stderr); status != 0 || stdout.Len() != 0 {
+		t.Errorf("expected no diff. status=%d, got=%q", status, stdout.String())
+	}
+
+	if status := runFmt(nil, strings.NewReader("let = 1"), &stdout, &stderr); status != 1 {
+		t.Errorf("expected status 1 for parse error. got=%d", status)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	b := "1\nTWO\n3\n4\n5\n6\n7\n8\n9\n10\n11\n"
+
+	expected := "--- f.orig\n+++ f\n" +
+		"@@ -1,5 +1,5 @@\n 1\n-2\n+TWO\n 3\n 4\n 5\n" +
+		"@@ -9,4 +9,3 @@\n 9\n 10\n 11\n-12\n"
+	if got := unifiedDiff("f", []byte(a), []byte(b)); got != expected {
+		t.Errorf("wrong diff.\nwant=%q\ngot=%q", expected, got)
+	}
+}
@@ -0,0 +1,627 @@
+// Package format 将Monkey源代码格式化为统一的风格
+// 每条语句一行，代码块缩进四个空格，只在优先级需要时添加括号，保留 // 注释
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/token"
+)
+
+const indentation = "    "
+
+// 非运算符表达式的优先级，任何位置都不需要括号
+const atom = parser.INDEX + 1
+
+// Source 格式化源代码，源代码有语法错误时返回错误
+func Source(src []byte) ([]byte, error) {
+	l := lexer.New(string(src))
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("parser errors:\n\t%s", strings.Join(p.Errors(), "\n\t"))
+	}
+
+	pr := &printer{src: strings.Split(string(src), "\n"), comments: l.Comments()}
+	pr.statements(program.Statements, math.MaxInt)
+	if pr.buf.Len() > 0 {
+		pr.write("\n")
+	}
+	return pr.buf.Bytes(), nil
+}
+
+// Node 格式化AST节点，例如宏展开的结果，不包含注释
+func Node(node ast.Node) string {
+	p := &printer{}
+	switch nodeT := node.(type) {
+	case *ast.Program:
+		p.statements(nodeT.Statements, math.MaxInt)
+	case ast.Statement:
+		p.statement(nodeT, false)
+	case ast.Expression:
+		p.expression(nodeT, parser.LOWEST)
+	}
+	return p.buf.String()
+}
+
+type printer struct {
+	src      []string // 源代码的每一行，用于保留空行和识别行尾注释
+	buf      bytes.Buffer
+	indent   int
+	comments []token.Token // 尚未输出的注释，按出现的顺序
+}
+
+func (p *printer) write(s string) {
+	p.buf.WriteString(s)
+}
+
+func (p *printer) newline() {
+	p.write("\n")
+	p.write(strings.Repeat(indentation, p.indent))
+}
+
+// 输出一组语句，每条语句一行，语句之间的空行最多保留一个
+// end为结束的}所在的行，之前的注释都属于这组语句
+func (p *printer) statements(stmts []ast.Statement, end int) {
+	printed := false
+	last := 0 // 上一个输出的语句或注释在源代码中的行
+
+	separate := func(line int) {
+		if printed {
+			// 空行不带缩进，只在下一行缩进
+			if p.blankBetween(last, line) {
+				p.write("\n")
+			}
+			p.newline()
+		}
+		printed = true
+		if line > 0 {
+			last = line
+		}
+	}
+	comment := func() {
+		separate(p.comments[0].Line)
+		p.write(p.comments[0].Literal)
+		p.comments = p.comments[1:]
+	}
+
+	for i, stmt := range stmts {
+		first, final := lines(stmt)
+		for p.hasCommentBefore(first) {
+			comment()
+		}
+
+		// 没有位置信息的节点，例如宏生成的代码，first为0
+		separate(first)
+		var next ast.Statement
+		limit := end
+		if i+1 < len(stmts) {
+			next = stmts[i+1]
+			if nextFirst, _ := lines(next); nextFirst > 0 {
+				limit = nextFirst
+			}
+		}
+		// 代码块中最后一条表达式语句是代码块的值，省略分号
+		p.statement(stmt, needSemicolon(stmt, next) && !(next == nil && end != math.MaxInt))
+
+		// 行尾的注释，可能位于语句最后一个词法单元之后的行，例如多行哈希字面量的}之后
+		if len(p.comments) > 0 && final > 0 {
+			c := p.comments[0]
+			if c.Line >= final && c.Line < limit && p.codeBefore(c) {
+				p.write(" " + c.Literal)
+				p.comments = p.comments[1:]
+				final = c.Line
+			}
+		}
+		if final > 0 {
+			last = final
+		}
+	}
+
+	for p.hasCommentBefore(end) {
+		comment()
+	}
+}
+
+// 以}结尾的表达式语句后面的分号可以省略，
+// 除非下一条语句以( [ -开头，此时省略分号会与上一条语句合并为调用、索引或减法
+func needSemicolon(stmt, next ast.Statement) bool {
+	es, ok := stmt.(*ast.ExpressionStatement)
+	if !ok {
+		return true
+	}
+	switch es.Expression.(type) {
+	case *ast.IfExpression, *ast.MatchExpression, *ast.TryExpression:
+	default:
+		return true
+	}
+
+	nextES, ok := next.(*ast.ExpressionStatement)
+	if !ok {
+		return false
+	}
+	switch leading(nextES.Expression) {
+	case "(", "[", "-":
+		return true
+	}
+	return false
+}
+
+// 表达式格式化后的第一个词法单元，只关心( [和前缀运算符
+func leading(exp ast.Expression) string {
+	var left ast.Expression
+	min := atom
+	switch expT := exp.(type) {
+	case *ast.InfixExpression:
+		left, min = expT.Left, precedence(expT)
+	case *ast.CallExpression:
+		left, min = expT.Function, parser.CALL
+	case *ast.IndexExpression:
+		left, min = expT.Left, parser.INDEX
+	case *ast.DotExpression:
+		left, min = expT.Left, parser.INDEX
+	case *ast.PrefixExpression:
+		return expT.Operator
+	case *ast.ArrayLiteral:
+		return "["
+	default:
+		return ""
+	}
+
+	if precedence(left) < min {
+		return "("
+	}
+	return leading(left)
+}
+
+func (p *printer) statement(stmt ast.Statement, semicolon bool) {
+	switch stmtT := stmt.(type) {
+	case *ast.LetStatement:
+		if stmtT.Constant() {
+			p.write("const ")
+		} else {
+			p.write("let ")
+		}
+		if stmtT.Pattern != nil {
+			p.expression(stmtT.Pattern, parser.LOWEST)
+		} else {
+			p.write(stmtT.Name.Value)
+		}
+		p.write(" = ")
+		p.expression(stmtT.Value, parser.LOWEST)
+		p.write(";")
+	case *ast.ReturnStatement:
+		p.write("return ")
+		p.expression(stmtT.ReturnValue, parser.LOWEST)
+		p.write(";")
+	case *ast.ThrowStatement:
+		p.write("throw ")
+		p.expression(stmtT.Value, parser.LOWEST)
+		p.write(";")
+	case *ast.StructStatement:
+		p.write("struct " + stmtT.Name.Value + " {")
+		if len(stmtT.Fields) > 0 {
+			p.write(" " + identifiers(stmtT.Fields) + " ")
+		}
+		p.write("}")
+	case *ast.ExpressionStatement:
+		p.expression(stmtT.Expression, parser.LOWEST)
+		if semicolon {
+			p.write(";")
+		}
+	}
+}
+
+// 表达式的优先级低于min时添加括号
+func (p *printer) expression(exp ast.Expression, min int) {
+	if precedence(exp) < min {
+		p.write("(")
+		defer p.write(")")
+	}
+
+	switch expT := exp.(type) {
+	case *ast.Identifier:
+		p.write(expT.Value)
+	case *ast.IntegerLiteral:
+		p.write(strconv.FormatInt(expT.Value, 10))
+	case *ast.StringLiteral:
+		p.write(`"` + expT.Value + `"`)
+	case *ast.Boolean:
+		p.write(strconv.FormatBool(expT.Value))
+	case *ast.NullLiteral:
+		p.write("null")
+	case *ast.PrefixExpression:
+		p.write(expT.Operator)
+		p.expression(expT.Right, parser.PREFIX)
+	case *ast.InfixExpression:
+		// 左结合，右侧优先级相同时也需要括号
+		prec := precedence(expT)
+		p.expression(expT.Left, prec)
+		p.write(" " + expT.Operator + " ")
+		p.expression(expT.Right, prec+1)
+	case *ast.IfExpression:
+		p.write("if (")
+		p.expression(expT.Condition, parser.LOWEST)
+		p.write(") ")
+		p.block(expT.Consequence)
+		if expT.Alternative != nil {
+			p.write(" else ")
+			p.block(expT.Alternative)
+		}
+	case *ast.FunctionLiteral:
+		p.write("fn(")
+		p.parameters(expT)
+		p.write(") ")
+		p.block(expT.Body)
+	case *ast.MacroLiteral:
+		p.write("macro(" + identifiers(expT.Parameters) + ") ")
+		p.block(expT.Body)
+	case *ast.CallExpression:
+		p.expression(expT.Function, parser.CALL)
+		items := p.expressionItems(expT.Arguments)
+		for _, arg := range expT.NamedArguments {
+			arg := arg
+			items = append(items, newItem(func() {
+				p.write(arg.Name.Value + ": ")
+				p.expression(arg.Value, parser.LOWEST)
+			}, arg))
+		}
+		// 调用参数和数组元素不支持结尾的逗号
+		p.list("(", ")", items, expT.Token, expT.Rparen, false, false)
+	case *ast.ArrayLiteral:
+		p.list("[", "]", p.expressionItems(expT.Elements), expT.Token, expT.Rbracket, false, false)
+	case *ast.HashLiteral:
+		p.hash(expT)
+	case *ast.IndexExpression:
+		p.expression(expT.Left, parser.INDEX)
+		p.write("[")
+		p.expression(expT.Index, parser.LOWEST)
+		p.write("]")
+	case *ast.DotExpression:
+		p.expression(expT.Left, parser.INDEX)
+		p.write("." + expT.Name.Value)
+	case *ast.ArrayPattern:
+		p.write("[")
+		p.expressions(expT.Elements)
+		if expT.Rest != nil {
+			if len(expT.Elements) > 0 {
+				p.write(", ")
+			}
+			p.write("..." + expT.Rest.Value)
+		}
+		p.write("]")
+	case *ast.HashPattern:
+		p.write("{")
+		for i, key := range expT.Keys {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.hashPatternPair(key, expT.Values[i])
+		}
+		p.write("}")
+	case *ast.MatchExpression:
+		p.match(expT)
+	case *ast.TryExpression:
+		p.write("try ")
+		p.block(expT.Block)
+		if expT.Catch != nil {
+			p.write(" catch (" + expT.CatchParam.Value + ") ")
+			p.block(expT.Catch)
+		}
+		if expT.Finally != nil {
+			p.write(" finally ")
+			p.block(expT.Finally)
+		}
+	default:
+		// 不会出现在语法分析结果中的节点
+		p.write(exp.String())
+	}
+}
+
+func (p *printer) expressions(exps []ast.Expression) {
+	for i, exp := range exps {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.expression(exp, parser.LOWEST)
+	}
+}
+
+func (p *printer) parameters(fl *ast.FunctionLiteral) {
+	for i, param := range fl.Parameters {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.write(param.Value)
+		if def, ok := fl.Defaults[param.Value]; ok {
+			p.write(" = ")
+			p.expression(def, parser.LOWEST)
+		}
+	}
+	if fl.Rest != nil {
+		if len(fl.Parameters) > 0 {
+			p.write(", ")
+		}
+		p.write("..." + fl.Rest.Value)
+	}
+}
+
+// 代码块，源代码中只占一行并且只有一条简单语句时保持在一行
+func (p *printer) block(bs *ast.BlockStatement) {
+	if len(bs.Statements) == 0 && !p.hasCommentBefore(bs.Rbrace.Line) {
+		p.write("{}")
+		return
+	}
+
+	// //注释会延续到行尾，只占一行的代码块中不会有注释
+	if len(bs.Statements) == 1 && bs.Token.Line > 0 && bs.Token.Line == bs.Rbrace.Line {
+		inline := &printer{indent: p.indent}
+		inline.statement(bs.Statements[0], false)
+		if !strings.Contains(inline.buf.String(), "\n") {
+			p.write("{ " + inline.buf.String() + " }")
+			return
+		}
+	}
+
+	p.write("{")
+	p.indent++
+	p.newline()
+	p.statements(bs.Statements, bs.Rbrace.Line)
+	p.indent--
+	p.newline()
+	p.write("}")
+}
+
+// 源代码中from和to两行之间是否有空行
+func (p *printer) blankBetween(from, to int) bool {
+	if from <= 0 || to > len(p.src) {
+		return false
+	}
+	for line := from + 1; line < to; line++ {
+		if strings.TrimSpace(p.src[line-1]) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// 注释所在行的注释前面是否有代码
+func (p *printer) codeBefore(comment token.Token) bool {
+	if comment.Line > len(p.src) {
+		return false
+	}
+	return strings.TrimSpace(p.src[comment.Line-1][:comment.Column-1]) != ""
+}
+
+// 是否还有位于line之前的注释
+func (p *printer) hasCommentBefore(line int) bool {
+	return len(p.comments) > 0 && line > 0 && p.comments[0].Line < line
+}
+
+// 哈希字面量，源代码中跨越多行时每个键值对一行
+func (p *printer) hash(hl *ast.HashLiteral) {
+	multiline := false
+	items := make([]item, len(hl.Pairs))
+	for i, pair := range hl.Pairs {
+		pair := pair
+		if first, _ := lines(pair.Key); first > hl.Token.Line && hl.Token.Line > 0 {
+			multiline = true
+		}
+		items[i] = newItem(func() {
+			p.expression(pair.Key, parser.LOWEST)
+			p.write(": ")
+			p.expression(pair.Value, parser.LOWEST)
+		}, pair.Key, pair.Value)
+	}
+	p.list("{", "}", items, hl.Token, hl.Rbrace, multiline, true)
+}
+
+// 简写形式{name}的键由标识符词法单元生成，键为标识符时不加引号
+func (p *printer) hashPatternPair(key, value ast.Expression) {
+	if str, ok := key.(*ast.StringLiteral); ok && str.Token.Type == token.IDENT {
+		if ident, ok := value.(*ast.Identifier); ok && ident.Value == str.Value {
+			p.write(ident.Value)
+			return
+		}
+		p.write(str.Value)
+	} else {
+		p.expression(key, parser.LOWEST)
+	}
+	p.write(": ")
+	p.expression(value, parser.LOWEST)
+}
+
+// 每个分支一行，以逗号结尾
+func (p *printer) match(me *ast.MatchExpression) {
+	p.write("match (")
+	p.expression(me.Subject, parser.LOWEST)
+	p.write(") ")
+
+	items := make([]item, len(me.Arms))
+	for i, arm := range me.Arms {
+		arm := arm
+		items[i] = newItem(func() {
+			p.expression(arm.Pattern, parser.LOWEST)
+			if arm.Guard != nil {
+				p.write(" if ")
+				p.expression(arm.Guard, parser.LOWEST)
+			}
+			p.write(" => ")
+			p.expression(arm.Body, parser.LOWEST)
+		}, arm)
+	}
+	p.list("{", "}", items, me.Token, me.Rbrace, true, true)
+}
+
+// 列表中的一个元素：参数、数组元素、键值对或match分支
+type item struct {
+	first, last token.Token // 元素在源代码中的第一个和最后一个词法单元
+	print       func()
+}
+
+func newItem(print func(), nodes ...ast.Node) item {
+	it := item{print: print}
+	for _, node := range nodes {
+		first, last := bounds(node)
+		if first.Line > 0 && (it.first.Line == 0 || before(first, it.first)) {
+			it.first = first
+		}
+		if last.Line > 0 && (it.last.Line == 0 || before(it.last, last)) {
+			it.last = last
+		}
+	}
+	return it
+}
+
+func (p *printer) expressionItems(exps []ast.Expression) []item {
+	items := make([]item, len(exps))
+	for i, exp := range exps {
+		exp := exp
+		items[i] = newItem(func() { p.expression(exp, parser.LOWEST) }, exp)
+	}
+	return items
+}
+
+// 输出用open和close包围的列表，multiline时每个元素一行，comma表示每个元素后面都加逗号
+// 元素之间有注释时也按多行输出，注释跟随在它之后的元素之前，或者行尾注释跟随在同一行的元素之后
+func (p *printer) list(open, close string, items []item, openTok, closeTok token.Token, multiline, comma bool) {
+	if !multiline && !p.commentBetweenItems(items, openTok, closeTok) {
+		p.write(open)
+		for i, it := range items {
+			if i > 0 {
+				p.write(", ")
+			}
+			it.print()
+		}
+		p.write(close)
+		return
+	}
+
+	p.write(open)
+	p.indent++
+	for i, it := range items {
+		for len(p.comments) > 0 && before(p.comments[0], it.first) {
+			p.newline()
+			p.write(p.comments[0].Literal)
+			p.comments = p.comments[1:]
+		}
+
+		p.newline()
+		it.print()
+		if comma || i+1 < len(items) {
+			p.write(",")
+		}
+
+		// 行尾注释，位于下一个元素和结束符号之前
+		if len(p.comments) > 0 {
+			c := p.comments[0]
+			if c.Line == it.last.Line && (i+1 == len(items) || before(c, items[i+1].first)) && before(c, closeTok) {
+				p.write(" " + c.Literal)
+				p.comments = p.comments[1:]
+			}
+		}
+	}
+	for len(p.comments) > 0 && before(p.comments[0], closeTok) {
+		p.newline()
+		p.write(p.comments[0].Literal)
+		p.comments = p.comments[1:]
+	}
+	p.indent--
+	p.newline()
+	p.write(close)
+}
+
+// 列表的开始和结束符号之间，是否有不在任何元素内部的注释
+func (p *printer) commentBetweenItems(items []item, openTok, closeTok token.Token) bool {
+	for _, c := range p.comments {
+		if !before(c, closeTok) {
+			return false
+		}
+		if !before(openTok, c) {
+			continue
+		}
+		inside := false
+		for _, it := range items {
+			if !before(c, it.first) && !before(it.last, c) {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			return true
+		}
+	}
+	return false
+}
+
+func identifiers(idents []*ast.Identifier) string {
+	names := make([]string, len(idents))
+	for i, ident := range idents {
+		names[i] = ident.Value
+	}
+	return strings.Join(names, ", ")
+}
+
+// 运算符表达式的优先级与语法分析器一致，其他表达式不需要括号
+func precedence(exp ast.Expression) int {
+	switch expT := exp.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(token.TokenType(expT.Operator))
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	default:
+		return atom
+	}
+}
+
+// 节点在源代码中的第一行和最后一行，没有位置信息时为0
+func lines(node ast.Node) (first, last int) {
+	firstTok, lastTok := bounds(node)
+	return firstTok.Line, lastTok.Line
+}
+
+// 节点在源代码中的第一个和最后一个词法单元，没有位置信息时行号为0
+func bounds(node ast.Node) (first, last token.Token) {
+	record := func(tok token.Token) {
+		if tok.Line == 0 {
+			return
+		}
+		if first.Line == 0 || before(tok, first) {
+			first = tok
+		}
+		if last.Line == 0 || before(last, tok) {
+			last = tok
+		}
+	}
+
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n == nil {
+			return false
+		}
+		v := reflect.Indirect(reflect.ValueOf(n))
+		// Token以及结束的括号
+		for _, name := range []string{"Token", "Rbrace", "Rparen", "Rbracket"} {
+			if field := v.FieldByName(name); field.IsValid() {
+				record(field.Interface().(token.Token))
+			}
+		}
+		return true
+	})
+	return first, last
+}
+
+// a在源代码中是否位于b之前，位置未知(行号为0)时为false
+func before(a, b token.Token) bool {
+	if a.Line == 0 || b.Line == 0 {
+		return false
+	}
+	return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+}
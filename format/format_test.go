@@ -0,0 +1,130 @@
+package format
+
+import (
+	"testing"
+
+	"monkey/lexer"
+	"monkey/parser"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = ((1 + 2) * 3)", "let x = (1 + 2) * 3;\n"},
+		{"1 - (2 - 3); (1 - 2) - 3; 1 + (2 * 3); -(a + b); !(-a); (-a)(b)", "1 - (2 - 3);\n1 - 2 - 3;\n1 + 2 * 3;\n-(a + b);\n!-a;\n(-a)(b);\n"},
+		{"(a < b) == (c > d); (a == b) < c", "a < b == c > d;\n(a == b) < c;\n"},
+		{"a.b[0](c).d; (a + b).c; (a + b)[0]", "a.b[0](c).d;\n(a + b).c;\n(a + b)[0];\n"},
+		{"add(1,2, b:3)", "add(1, 2, b: 3);\n"},
+		{"const  limit=10", "const limit = 10;\n"},
+		{"let [a,[b],...c]=arr; let {name, \"age\": a, nested: {x}} = h;",
+			"let [a, [b], ...c] = arr;\nlet {name, \"age\": a, nested: {x}} = h;\n"},
+		{"struct Point {x,y,}", "struct Point { x, y }\n"},
+		{"fn(){}", "fn() {};\n"},
+		// 只占一行的代码块保持在一行
+		{"let f = fn(x){x*2}", "let f = fn(x) { x * 2 };\n"},
+		{"let f = fn(x){\nlet y = x;\ny}", "let f = fn(x) {\n    let y = x;\n    y\n};\n"},
+		// 以}结尾的语句只在需要时保留分号
+		{"if (a) { b }; puts(1)", "if (a) { b }\nputs(1);\n"},
+		{"if (a) { b }; (-c)(1); if (a) { b }; [1]; if (a) { b }; -1", "if (a) { b };\n(-c)(1);\nif (a) { b };\n[1];\nif (a) { b };\n-1;\n"},
+		{"match (x) { 0 => \"zero\", [a, ...t] if len(t) > 0 => a, _ => null }",
+			"match (x) {\n    0 => \"zero\",\n    [a, ...t] if len(t) > 0 => a,\n    _ => null,\n}\n"},
+		{"try { throw \"e\" } catch (e) { e } finally { puts(1) }",
+			"try { throw \"e\"; } catch (e) { e } finally { puts(1) }\n"},
+		{"let h = {\"a\": 1, \"b\": 2}", "let h = {\"a\": 1, \"b\": 2};\n"},
+		{"let h = {\n\"a\": 1, \"b\": {\n\"c\": 2}}", "let h = {\n    \"a\": 1,\n    \"b\": {\n        \"c\": 2,\n    },\n};\n"},
+		{"let m = macro(a, b) { quote(unquote(b) - unquote(a)) }", "let m = macro(a, b) { quote(unquote(b) - unquote(a)) };\n"},
+		// 空行最多保留一个
+		{"let a = 1;\n\n\n\nlet b = 2;\nlet c = 3;", "let a = 1;\n\nlet b = 2;\nlet c = 3;\n"},
+		// 代码块中的空行不带缩进
+		{"let f = fn(){\nlet a = 1;\n\nlet b = 2;\nb}", "let f = fn() {\n    let a = 1;\n\n    let b = 2;\n    b\n};\n"},
+		{"if (x) {\nlet g = fn() {\nlet a = 1;\n\n\n// c\na\n};\ng()\n}",
+			"if (x) {\n    let g = fn() {\n        let a = 1;\n\n        // c\n        a\n    };\n    g()\n}\n"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		testFormat(t, tt.input, tt.expected)
+	}
+}
+
+func TestSourceComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"// only comment", "// only comment\n"},
+		{"// header\nlet x = 1; // one\n\n// tail", "// header\nlet x = 1; // one\n\n// tail\n"},
+		{"let f = fn() { // open\n  // inside\n  1\n  // before close\n}; // close",
+			"let f = fn() {\n    // open\n    // inside\n    1\n    // before close\n}; // close\n"},
+		{"let f = fn() {\n// only\n};", "let f = fn() {\n    // only\n};\n"},
+		{"let h = {\n  \"a\": 1\n}; // hash\nlet b = 2;", "let h = {\n    \"a\": 1,\n}; // hash\nlet b = 2;\n"},
+		// 多行表达式内部的注释跟随对应的键值对、参数和分支
+		{"let h = {\n \"a\": 1, // one\n \"b\": 2\n};",
+			"let h = {\n    \"a\": 1, // one\n    \"b\": 2,\n};\n"},
+		{"let h = {\n  // first\n  \"a\": 1,\n  \"b\": 2 // two\n  // end\n};",
+			"let h = {\n    // first\n    \"a\": 1,\n    \"b\": 2, // two\n    // end\n};\n"},
+		{"f(1, // one\n  2);", "f(\n    1, // one\n    2\n);\n"},
+		{"f(\n  // first\n  1,\n  b: 2 // named\n);", "f(\n    // first\n    1,\n    b: 2 // named\n);\n"},
+		{"let a = [1, // one\n  [2, // two\n  3]];", "let a = [\n    1, // one\n    [\n        2, // two\n        3\n    ]\n];\n"},
+		{"match (x) {\n  1 => \"one\", // one\n  // other\n  _ => \"many\"\n}",
+			"match (x) {\n    1 => \"one\", // one\n    // other\n    _ => \"many\",\n}\n"},
+		// 参数中函数体内的注释不会让调用变成多行
+		{"f(fn() {\n  // body\n  1\n});", "f(fn() {\n    // body\n    1\n});\n"},
+		// 列表之后的注释仍然是语句的行尾注释
+		{"f(1, 2); // call", "f(1, 2); // call\n"},
+	}
+
+	for _, tt := range tests {
+		testFormat(t, tt.input, tt.expected)
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	_, err := Source([]byte("let = 5;"))
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	expected := "parser errors:\n\texpected next token to be IDENT, got = instead\n\tno prefix parse function for = found"
+	if err.Error() != expected {
+		t.Errorf("wrong error. want=%q, got=%q", expected, err.Error())
+	}
+}
+
+func TestNode(t *testing.T) {
+	program := parser.New(lexer.New("let f = fn(x) { ((x + 1) * 2) }; f(1)")).ParseProgram()
+
+	expected := "let f = fn(x) { (x + 1) * 2 };\nf(1);"
+	if got := Node(program); got != expected {
+		t.Errorf("wrong result. want=%q, got=%q", expected, got)
+	}
+}
+
+// 格式化结果与预期一致，并且再次格式化结果不变，语义与原始代码相同
+func testFormat(t *testing.T, input, expected string) {
+	t.Helper()
+
+	formatted, err := Source([]byte(input))
+	if err != nil {
+		t.Fatalf("format %q failed: %s", input, err)
+	}
+	if string(formatted) != expected {
+		t.Errorf("wrong format for %q.\nwant=%q\ngot=%q", input, expected, formatted)
+		return
+	}
+
+	again, err := Source(formatted)
+	if err != nil {
+		t.Fatalf("format %q failed: %s", formatted, err)
+	}
+	if string(again) != string(formatted) {
+		t.Errorf("format is not idempotent.\nfirst=%q\nsecond=%q", formatted, again)
+	}
+
+	original := parser.New(lexer.New(input)).ParseProgram()
+	reparsed := parser.New(lexer.New(string(formatted))).ParseProgram()
+	if original.String() != reparsed.String() {
+		t.Errorf("format changed the program.\nwant=%q\ngot=%q", original.String(), reparsed.String())
+	}
+}
@@ -0,0 +1,229 @@
+package interpreter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+)
+
+/*
+嵌入式解释器
+宿主程序无需手动组装 词法分析 -> 语法分析 -> 宏扩展 -> 求值 的流程
+*/
+
+// ParseError 语法分析阶段的错误
+type ParseError struct {
+	Messages []string
+}
+
+func (pe *ParseError) Error() string {
+	return "parser errors:\n\t" + strings.Join(pe.Messages, "\n\t")
+}
+
+// MacroError 宏展开阶段的错误
+type MacroError struct {
+	Diagnostics []evaluator.Diagnostic
+}
+
+func (me *MacroError) Error() string {
+	messages := make([]string, len(me.Diagnostics))
+	for i, d := range me.Diagnostics {
+		messages[i] = d.String()
+	}
+	return "macro expansion errors:\n\t" + strings.Join(messages, "\n\t")
+}
+
+// RuntimeError 求值阶段产生的错误
+type RuntimeError struct {
+	Err *object.Error
+}
+
+func (re *RuntimeError) Error() string {
+	return re.Err.Message
+}
+
+// Traceback 带调用栈的错误信息
+func (re *RuntimeError) Traceback() string {
+	return re.Err.Traceback()
+}
+
+type Interpreter struct {
+	env      *object.Environment // 全局环境，多次Run之间共享
+	macroEnv *object.Environment // 宏定义所在的环境
+	eval     *evaluator.Evaluator
+	globals  []global // WithGlobal绑定的变量，在所有选项执行之后写入全局环境
+	err      error    // 创建时选项产生的错误，由之后的Run和Call返回
+}
+
+type global struct {
+	name string
+	val  object.Object
+}
+
+// Option 在创建解释器时对其进行配置
+type Option func(*Interpreter)
+
+// WithEnvironment 使用已有的环境作为全局环境
+func WithEnvironment(env *object.Environment) Option {
+	return func(i *Interpreter) {
+		i.env = env
+	}
+}
+
+// WithGlobal 预先绑定一个全局变量，与WithEnvironment的先后顺序无关
+// 绑定失败(例如名称是环境中的常量)时，之后的Run和Call都返回该错误
+func WithGlobal(name string, val object.Object) Option {
+	return func(i *Interpreter) {
+		i.globals = append(i.globals, global{name, val})
+	}
+}
+
+// WithBuiltin 注册一个仅对该解释器可见的内置函数
+func WithBuiltin(name string, fn object.BuiltinFunction) Option {
+	return func(i *Interpreter) {
+		i.eval.RegisterBuiltin(name, fn)
+	}
+}
+
+// WithLimits 设置执行限制，每次Run或Call都会重新计算额度
+func WithLimits(limits evaluator.Limits) Option {
+	return func(i *Interpreter) {
+		i.eval.SetLimits(limits)
+	}
+}
+
+// WithRedeclareMode 设置在同一作用域中重复声明let时的处理方式
+func WithRedeclareMode(mode evaluator.RedeclareMode) Option {
+	return func(i *Interpreter) {
+		i.eval.SetRedeclareMode(mode)
+	}
+}
+
+// WithModuleLoader 设置import读取模块的方式，没有设置时import返回错误
+func WithModuleLoader(loader evaluator.ModuleLoader) Option {
+	return func(i *Interpreter) {
+		i.eval.SetModuleLoader(loader)
+	}
+}
+
+// FileLoader 从文件系统读取模块，路径相对于当前工作目录
+func FileLoader(path string) (string, error) {
+	src, err := os.ReadFile(path)
+	return string(src), err
+}
+
+func New(opts ...Option) *Interpreter {
+	i := &Interpreter{
+		env:  object.NewEnvironment(),
+		eval: evaluator.New(),
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	for _, g := range i.globals {
+		if err := i.Set(g.name, g.val); err != nil && i.err == nil {
+			i.err = err
+		}
+	}
+	// 与repl一致，宏环境包裹全局环境
+	i.macroEnv = object.NewEnclosedEnvironment(i.env)
+	return i
+}
+
+// Run 对一段源代码求值，返回最后一条语句的结果
+func (i *Interpreter) Run(src string) (object.Object, error) {
+	return i.RunContext(context.Background(), src)
+}
+
+// RunContext 与Run相同，ctx被取消时求值中止并返回错误
+func (i *Interpreter) RunContext(ctx context.Context, src string) (object.Object, error) {
+	if i.err != nil {
+		return nil, i.err
+	}
+	l := lexer.New(src)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &ParseError{Messages: p.Errors()}
+	}
+
+	i.eval.Reset()
+	i.eval.SetContext(ctx)
+	program = evaluator.DefineMacros(program, i.macroEnv)
+	expanded, diagnostics := i.eval.ExpandMacros(program, i.macroEnv)
+	if len(diagnostics) != 0 {
+		return nil, &MacroError{Diagnostics: diagnostics}
+	}
+
+	return result(i.eval.Eval(expanded, i.env))
+}
+
+// RunFile 读取文件内容并求值
+func (i *Interpreter) RunFile(path string) (object.Object, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return i.Run(string(src))
+}
+
+// Warnings 返回最近一次Run或Call产生的警告
+func (i *Interpreter) Warnings() []string {
+	return i.eval.Warnings()
+}
+
+// RegisterBuiltin 注册一个仅对该解释器可见的内置函数
+func (i *Interpreter) RegisterBuiltin(name string, fn object.BuiltinFunction) {
+	i.eval.RegisterBuiltin(name, fn)
+}
+
+// Set 设置全局变量，名称是常量时返回错误
+func (i *Interpreter) Set(name string, val object.Object) error {
+	if errObj, ok := i.env.Set(name, val).(*object.Error); ok {
+		return &RuntimeError{Err: errObj}
+	}
+	return nil
+}
+
+// Err 返回创建解释器时选项产生的错误
+func (i *Interpreter) Err() error {
+	return i.err
+}
+
+// Get 获取全局变量
+func (i *Interpreter) Get(name string) (object.Object, bool) {
+	return i.env.Get(name)
+}
+
+// Call 调用全局环境中名为fnName的函数
+func (i *Interpreter) Call(fnName string, args ...object.Object) (object.Object, error) {
+	return i.CallContext(context.Background(), fnName, args...)
+}
+
+// CallContext 与Call相同，ctx被取消时求值中止并返回错误
+func (i *Interpreter) CallContext(ctx context.Context, fnName string, args ...object.Object) (object.Object, error) {
+	if i.err != nil {
+		return nil, i.err
+	}
+	fn, ok := i.env.Get(fnName)
+	if !ok {
+		return nil, fmt.Errorf("function not found: %s", fnName)
+	}
+	i.eval.Reset()
+	i.eval.SetContext(ctx)
+	return result(i.eval.ApplyFunction(fn, args))
+}
+
+// 将求值结果中的错误对象转换为Go的error
+func result(obj object.Object) (object.Object, error) {
+	if errObj, ok := obj.(*object.Error); ok {
+		return nil, &RuntimeError{Err: errObj}
+	}
+	return obj, nil
+}
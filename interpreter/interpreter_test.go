@@ -0,0 +1,287 @@
+package interpreter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"monkey/evaluator"
+	"monkey/object"
+)
+
+func TestRun(t *testing.T) {
+	i := New()
+
+	result, err := i.Run("let add = fn(x, y) { x + y }; add(1, 2);")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testIntegerObject(t, result, 3)
+
+	// 全局环境在多次Run之间共享
+	result, err = i.Run("add(3, 4)")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testIntegerObject(t, result, 7)
+}
+
+func TestRunMacros(t *testing.T) {
+	i := New()
+
+	_, err := i.Run(`let reverse = macro(a, b) { quote(unquote(b) - unquote(a)); };`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	result, err := i.Run("reverse(2, 10)")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testIntegerObject(t, result, 8)
+
+	// 宏可以在之后的Run中再次使用
+	result, err = i.Run("reverse(1, reverse(1, 5))")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testIntegerObject(t, result, 3)
+}
+
+func TestRunErrors(t *testing.T) {
+	i := New()
+
+	_, err := i.Run("let = 5;")
+	if _, ok := err.(*ParseError); !ok {
+		t.Errorf("err is not *ParseError. got=%T (%+v)", err, err)
+	}
+
+	_, err = i.Run("5 + true")
+	runtimeErr, ok := err.(*RuntimeError)
+	if !ok {
+		t.Fatalf("err is not *RuntimeError. got=%T (%+v)", err, err)
+	}
+	if runtimeErr.Error() != "type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("wrong error message. got=%q", runtimeErr.Error())
+	}
+}
+
+func TestRunMacroErrors(t *testing.T) {
+	i := New()
+
+	_, err := i.Run("let m = macro(a) { quote(unquote(a)); }; m();")
+	macroErr, ok := err.(*MacroError)
+	if !ok {
+		t.Fatalf("err is not *MacroError. got=%T (%+v)", err, err)
+	}
+	expected := "macro expansion errors:\n\tline 1, column 42: wrong number of arguments to macro `m`: want=1, got=0"
+	if macroErr.Error() != expected {
+		t.Errorf("wrong error message. want=%q, got=%q", expected, macroErr.Error())
+	}
+}
+
+func TestSetGetCall(t *testing.T) {
+	i := New(WithGlobal("base", &object.Integer{Value: 10}))
+
+	if _, err := i.Run("let addBase = fn(x) { x + base };"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	i.Set("base", &object.Integer{Value: 100})
+	result, err := i.Call("addBase", &object.Integer{Value: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testIntegerObject(t, result, 101)
+
+	if _, ok := i.Get("addBase"); !ok {
+		t.Errorf("addBase not found")
+	}
+
+	if _, err := i.Call("missing"); err == nil {
+		t.Errorf("expected error calling missing function")
+	}
+}
+
+func TestRunFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.mk")
+	if err := os.WriteFile(path, []byte("let x = 2; x * 21"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := New().RunFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testIntegerObject(t, result, 42)
+}
+
+func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
+	result, ok := obj.(*object.Integer)
+	if !ok {
+		t.Errorf("object is not Integer. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%d, want=%d", result.Value, expected)
+		return false
+	}
+	return true
+}
+
+func TestWithBuiltin(t *testing.T) {
+	greet := func(args ...object.Object) object.Object {
+		return &object.String{Value: "hello " + args[0].Inspect()}
+	}
+	i := New(WithBuiltin("greet", greet))
+
+	result, err := i.Run(`greet("monkey")`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if str, ok := result.(*object.String); !ok || str.Value != "hello monkey" {
+		t.Errorf("wrong result. got=%+v", result)
+	}
+
+	if _, err := New().Run(`greet("monkey")`); err == nil {
+		t.Errorf("builtin should not be visible to another interpreter")
+	}
+}
+
+func TestWithLimits(t *testing.T) {
+	i := New(WithLimits(evaluator.Limits{MaxDepth: 100}))
+
+	_, err := i.Run("let loop = fn() { 1 + loop() }; loop();")
+	runtimeErr, ok := err.(*RuntimeError)
+	if !ok {
+		t.Fatalf("err is not *RuntimeError. got=%T (%+v)", err, err)
+	}
+	if runtimeErr.Err.Kind != object.LIMIT_ERROR {
+		t.Errorf("wrong error kind. got=%q", runtimeErr.Err.Kind)
+	}
+
+	// 每次执行重新计算额度
+	result, err := i.Run("let count = fn(n) { if (n > 0) { count(n - 1) } else { n } }; count(90);")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testIntegerObject(t, result, 0)
+}
+
+func TestWithRedeclareMode(t *testing.T) {
+	i := New(WithRedeclareMode(evaluator.RedeclareWarn))
+
+	result, err := i.Run("let x = 1;\nlet x = 2; x;")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testIntegerObject(t, result, 2)
+
+	warnings := i.Warnings()
+	if len(warnings) != 1 || warnings[0] != "line 2, column 1: `x` redeclared in the same scope" {
+		t.Errorf("wrong warnings. got=%q", warnings)
+	}
+
+	// 不同的Run之间共享全局环境，常量同样不能被覆盖
+	if _, err := i.Run("const limit = 10;"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	_, err = i.Run("let limit = 20;")
+	if err == nil || err.Error() != "cannot reassign constant `limit`" {
+		t.Errorf("expected const error. got=%v", err)
+	}
+}
+
+func TestRunContext(t *testing.T) {
+	i := New()
+	if _, err := i.Run("let spin = fn(n) { if (n > 0) { spin(n - 1) + spin(n - 1) } else { 0 } };"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := i.CallContext(ctx, "spin", &object.Integer{Value: 40})
+	runtimeErr, ok := err.(*RuntimeError)
+	if !ok {
+		t.Fatalf("err is not *RuntimeError. got=%T (%+v)", err, err)
+	}
+	if runtimeErr.Err.Kind != object.CANCEL_ERROR {
+		t.Errorf("wrong error kind. got=%q", runtimeErr.Err.Kind)
+	}
+
+	// 新的调用不受之前context的影响
+	result, err := i.Run("spin(3)")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testIntegerObject(t, result, 0)
+}
+
+func TestWithGlobalAndEnvironment(t *testing.T) {
+	env := object.NewEnvironment()
+	env.Set("x", &object.Integer{Value: 1})
+	i := New(WithGlobal("y", &object.Integer{Value: 2}), WithEnvironment(env))
+
+	result, err := i.Run("x + y")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testIntegerObject(t, result, 3)
+}
+
+func TestSetConst(t *testing.T) {
+	i := New()
+	if _, err := i.Run("const limit = 10;"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	err := i.Set("limit", &object.Integer{Value: 20})
+	if err == nil || err.Error() != "cannot reassign constant `limit`" {
+		t.Errorf("expected const error. got=%v", err)
+	}
+	if val, _ := i.Get("limit"); val.Inspect() != "10" {
+		t.Errorf("const was overwritten. got=%s", val.Inspect())
+	}
+
+	// 选项中的错误由之后的Run返回
+	env := object.NewEnvironment()
+	env.SetConst("limit", &object.Integer{Value: 10})
+	i = New(WithEnvironment(env), WithGlobal("limit", &object.Integer{Value: 20}))
+	if i.Err() == nil {
+		t.Fatalf("expected error from WithGlobal")
+	}
+	if _, err := i.Run("limit"); err != i.Err() {
+		t.Errorf("Run should return the option error. got=%v", err)
+	}
+	if _, err := i.Call("limit"); err != i.Err() {
+		t.Errorf("Call should return the option error. got=%v", err)
+	}
+}
+
+func TestWithModuleLoader(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib.mk")
+	if err := os.WriteFile(lib, []byte("let swap = macro(a, b) { quote(unquote(b) - unquote(a)) }; let base = 10;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	i := New(WithModuleLoader(FileLoader))
+	if _, err := i.Run(`let lib = import("` + filepath.ToSlash(lib) + `");`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// 顶层导入的宏在之后的Run中同样可用
+	result, err := i.Run("swap(2, lib.base)")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testIntegerObject(t, result, 8)
+
+	_, err = New().Run(`import("lib.mk")`)
+	if _, ok := err.(*MacroError); !ok {
+		t.Errorf("err is not *MacroError. got=%T (%+v)", err, err)
+	}
+}
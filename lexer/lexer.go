@@ -1,22 +1,36 @@
 package lexer
 
-import "monkey/token"
+import (
+	"strings"
+
+	"monkey/token"
+)
 
 type Lexer struct {
 	input        string
 	position     int  // 所输入字符串中的当前位置（指向当前字符）
 	readPosition int  // 所输入字符串中的当前读取位置（指向当前字符之后的一个字符）
 	ch           byte // 当前正在查看的字符
+	line         int  // 当前字符所在的行
+	column       int  // 当前字符所在的列
+
+	comments []token.Token // 跳过的注释，供格式化工具使用
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
 //  仅支持ASCII字符，不支持Unicode（需要将l.ch改为rune，同时修改下一个字符的读取方式）
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line += 1
+		l.column = 0
+	}
+	l.column += 1
+
 	if l.readPosition >= len(l.input) {
 		l.ch = 0 // nul字符的ASCII编码
 	} else {
@@ -41,6 +55,13 @@ func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 
 	l.skipWhitespace()
+	for l.ch == '/' && l.peekChar() == '/' {
+		l.comments = append(l.comments, l.readComment())
+		l.skipWhitespace()
+	}
+
+	// 记录词法单元起始位置
+	line, column := l.line, l.column
 
 	switch l.ch {
 	case '=':
@@ -52,6 +73,9 @@ func (l *Lexer) NextToken() token.Token {
 				Type:    token.EQ,
 				Literal: literal,
 			}
+		} else if l.peekChar() == '>' {
+			l.readChar()
+			tok = token.Token{Type: token.ARROW, Literal: "=>"}
 		} else {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
@@ -103,15 +127,25 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.RBRACKET, l.ch)
 	case ':':
 		tok = newToken(token.COLON, l.ch)
+	case '.':
+		if l.peekChar() == '.' && l.peekCharN(2) == '.' {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else {
+			tok = newToken(token.DOT, l.ch)
+		}
 	default:
 		// 对连续字母进行获取
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Line, tok.Column = line, column
 			return tok
 		} else if isDigit(l.ch) {
 			tok.Type = token.INT
 			tok.Literal = l.readNumber()
+			tok.Line, tok.Column = line, column
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
@@ -119,6 +153,7 @@ func (l *Lexer) NextToken() token.Token {
 	}
 
 	l.readChar()
+	tok.Line, tok.Column = line, column
 	return tok
 }
 
@@ -126,9 +161,26 @@ func newToken(tokenType token.TokenType, ch byte) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
 
+// 读取 // 开始直到行尾的注释
+func (l *Lexer) readComment() token.Token {
+	tok := token.Token{Type: token.COMMENT, Line: l.line, Column: l.column}
+	position := l.position
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+	tok.Literal = strings.TrimRight(l.input[position:l.position], " \t\r")
+	return tok
+}
+
+// Comments 返回目前为止跳过的注释，按出现的顺序
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
+// 标识符以字母或下划线开头，之后可以包含数字
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isLetter(l.ch) {
+	for isLetter(l.ch) || isDigit(l.ch) {
 		l.readChar()
 	}
 	return l.input[position:l.position]
@@ -165,3 +217,11 @@ func (l *Lexer) peekChar() byte {
 		return l.input[l.readPosition]
 	}
 }
+
+// 查看之后第n个字符，peekCharN(1)等同于peekChar()
+func (l *Lexer) peekCharN(n int) byte {
+	if l.readPosition+n-1 >= len(l.input) {
+		return 0
+	}
+	return l.input[l.readPosition+n-1]
+}
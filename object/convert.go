@@ -0,0 +1,489 @@
+package object
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+/*
+Go的值与Monkey对象之间的相互转换
+整数、浮点数 <-> Integer (monkey只支持整数，浮点数必须没有小数部分)
+字符串 <-> String
+布尔值 <-> Boolean
+切片、数组 <-> Array
+map、结构体 <-> Hash (结构体字段名可以通过`monkey:"name"`标签修改)
+函数 -> Builtin
+*/
+
+var (
+	objectType = reflect.TypeOf((*Object)(nil)).Elem()
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// FromGo 通过反射将Go的值转换为Monkey对象
+func FromGo(v interface{}) (Object, error) {
+	if v == nil {
+		return NULL, nil
+	}
+	return fromValue(reflect.ValueOf(v), visiting{})
+}
+
+// 正在转换的指针、map和切片，用于检测循环引用
+type visiting map[visit]bool
+
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int // 共享底层数组的切片长度可能不同
+}
+
+// 进入引用类型的值，值已经在转换路径上时说明存在循环引用
+func (vs visiting) enter(rv reflect.Value) (leave func(), err error) {
+	v := visit{ptr: rv.Pointer(), typ: rv.Type()}
+	if rv.Kind() == reflect.Slice {
+		v.len = rv.Len()
+	}
+	if vs[v] {
+		return nil, fmt.Errorf("cannot convert cyclic value of type %s", rv.Type())
+	}
+	vs[v] = true
+	return func() { delete(vs, v) }, nil
+}
+
+func fromValue(rv reflect.Value, vs visiting) (Object, error) {
+	if !rv.IsValid() {
+		return NULL, nil
+	}
+
+	// 已经是Monkey对象的值原样返回
+	if rv.Type().Implements(objectType) {
+		if rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return NULL, nil
+		}
+		return rv.Interface().(Object), nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		if rv.Bool() {
+			return TRUE, nil
+		}
+		return FALSE, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Integer{Value: rv.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return nil, fmt.Errorf("integer overflow: %d", u)
+		}
+		return &Integer{Value: int64(u)}, nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f != math.Trunc(f) || f >= 1<<63 || f < math.MinInt64 {
+			return nil, fmt.Errorf("cannot represent %v as INTEGER", f)
+		}
+		return &Integer{Value: int64(f)}, nil
+	case reflect.String:
+		return &String{Value: rv.String()}, nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice {
+			if rv.IsNil() {
+				return NULL, nil
+			}
+			if rv.Len() > 0 {
+				leave, err := vs.enter(rv)
+				if err != nil {
+					return nil, err
+				}
+				defer leave()
+			}
+		}
+		elements := make([]Object, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			el, err := fromValue(rv.Index(i), vs)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = el
+		}
+		return &Array{Elements: elements}, nil
+	case reflect.Map:
+		if rv.IsNil() {
+			return NULL, nil
+		}
+		leave, err := vs.enter(rv)
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+		hash := &Hash{Pairs: make(map[HashKey]HashPair)}
+		iter := rv.MapRange()
+		for iter.Next() {
+			key, err := fromValue(iter.Key(), vs)
+			if err != nil {
+				return nil, err
+			}
+			value, err := fromValue(iter.Value(), vs)
+			if err != nil {
+				return nil, err
+			}
+			if err := setHashPair(hash, key, value); err != nil {
+				return nil, err
+			}
+		}
+		return hash, nil
+	case reflect.Struct:
+		hash := &Hash{Pairs: make(map[HashKey]HashPair)}
+		rt := rv.Type()
+		for i := 0; i < rt.NumField(); i++ {
+			field := rt.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			value, err := fromValue(rv.Field(i), vs)
+			if err != nil {
+				return nil, err
+			}
+			if err := setHashPair(hash, &String{Value: fieldName(field)}, value); err != nil {
+				return nil, err
+			}
+		}
+		return hash, nil
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return NULL, nil
+		}
+		if rv.Kind() == reflect.Ptr {
+			leave, err := vs.enter(rv)
+			if err != nil {
+				return nil, err
+			}
+			defer leave()
+		}
+		return fromValue(rv.Elem(), vs)
+	case reflect.Func:
+		if rv.IsNil() {
+			return NULL, nil
+		}
+		return wrapFunc(rv), nil
+	default:
+		return nil, fmt.Errorf("cannot convert %s to monkey object", rv.Type())
+	}
+}
+
+func setHashPair(hash *Hash, key, value Object) error {
+	hashKey, ok := key.(Hashtable)
+	if !ok {
+		return fmt.Errorf("unusable as hash key: %s", key.Type())
+	}
+	hash.Pairs[hashKey.HashKey()] = HashPair{Key: key, Value: value}
+	return nil
+}
+
+func fieldName(field reflect.StructField) string {
+	if name := field.Tag.Get("monkey"); name != "" {
+		return name
+	}
+	return field.Name
+}
+
+// ToGo 将Monkey对象转换后写入target，target必须是非空指针
+func ToGo(obj Object, target interface{}) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("target must be a non-nil pointer, got %T", target)
+	}
+	return toValue(obj, rv.Elem())
+}
+
+func toValue(obj Object, rv reflect.Value) error {
+	if obj == nil {
+		obj = NULL
+	}
+
+	// 目标本身可以保存Monkey对象
+	if objectType.AssignableTo(rv.Type()) && rv.Kind() == reflect.Interface && rv.NumMethod() > 0 {
+		rv.Set(reflect.ValueOf(obj))
+		return nil
+	}
+	if reflect.TypeOf(obj).AssignableTo(rv.Type()) && rv.Kind() != reflect.Interface {
+		rv.Set(reflect.ValueOf(obj))
+		return nil
+	}
+
+	if _, ok := obj.(*Null); ok {
+		switch rv.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map, reflect.Func:
+			rv.Set(reflect.Zero(rv.Type()))
+			return nil
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.Interface:
+		if rv.NumMethod() > 0 {
+			return mismatch(obj, rv.Type())
+		}
+		value, err := natural(obj)
+		if err != nil {
+			return err
+		}
+		if value == nil {
+			rv.Set(reflect.Zero(rv.Type()))
+		} else {
+			rv.Set(reflect.ValueOf(value))
+		}
+		return nil
+	case reflect.Bool:
+		b, ok := obj.(*Boolean)
+		if !ok {
+			return mismatch(obj, rv.Type())
+		}
+		rv.SetBool(b.Value)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := obj.(*Integer)
+		if !ok {
+			return mismatch(obj, rv.Type())
+		}
+		if rv.OverflowInt(i.Value) {
+			return fmt.Errorf("integer %d overflows %s", i.Value, rv.Type())
+		}
+		rv.SetInt(i.Value)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, ok := obj.(*Integer)
+		if !ok {
+			return mismatch(obj, rv.Type())
+		}
+		if i.Value < 0 || rv.OverflowUint(uint64(i.Value)) {
+			return fmt.Errorf("integer %d overflows %s", i.Value, rv.Type())
+		}
+		rv.SetUint(uint64(i.Value))
+		return nil
+	case reflect.Float32, reflect.Float64:
+		i, ok := obj.(*Integer)
+		if !ok {
+			return mismatch(obj, rv.Type())
+		}
+		rv.SetFloat(float64(i.Value))
+		return nil
+	case reflect.String:
+		s, ok := obj.(*String)
+		if !ok {
+			return mismatch(obj, rv.Type())
+		}
+		rv.SetString(s.Value)
+		return nil
+	case reflect.Slice:
+		arr, ok := obj.(*Array)
+		if !ok {
+			return mismatch(obj, rv.Type())
+		}
+		slice := reflect.MakeSlice(rv.Type(), len(arr.Elements), len(arr.Elements))
+		for i, el := range arr.Elements {
+			if err := toValue(el, slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		rv.Set(slice)
+		return nil
+	case reflect.Array:
+		arr, ok := obj.(*Array)
+		if !ok {
+			return mismatch(obj, rv.Type())
+		}
+		if len(arr.Elements) != rv.Len() {
+			return fmt.Errorf("cannot convert ARRAY of length %d to %s", len(arr.Elements), rv.Type())
+		}
+		for i, el := range arr.Elements {
+			if err := toValue(el, rv.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		hash, ok := obj.(*Hash)
+		if !ok {
+			return mismatch(obj, rv.Type())
+		}
+		m := reflect.MakeMapWithSize(rv.Type(), len(hash.Pairs))
+		for _, pair := range hash.Pairs {
+			key := reflect.New(rv.Type().Key()).Elem()
+			if err := toValue(pair.Key, key); err != nil {
+				return err
+			}
+			value := reflect.New(rv.Type().Elem()).Elem()
+			if err := toValue(pair.Value, value); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, value)
+		}
+		rv.Set(m)
+		return nil
+	case reflect.Struct:
+		hash, ok := obj.(*Hash)
+		if !ok {
+			return mismatch(obj, rv.Type())
+		}
+		rt := rv.Type()
+		for i := 0; i < rt.NumField(); i++ {
+			field := rt.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			key := &String{Value: fieldName(field)}
+			pair, ok := hash.Pairs[key.HashKey()]
+			if !ok {
+				continue
+			}
+			if err := toValue(pair.Value, rv.Field(i)); err != nil {
+				return fmt.Errorf("field %s: %s", field.Name, err)
+			}
+		}
+		return nil
+	case reflect.Ptr:
+		elem := reflect.New(rv.Type().Elem())
+		if err := toValue(obj, elem.Elem()); err != nil {
+			return err
+		}
+		rv.Set(elem)
+		return nil
+	default:
+		return mismatch(obj, rv.Type())
+	}
+}
+
+// 目标为interface{}时转换为最自然的Go类型
+func natural(obj Object) (interface{}, error) {
+	switch objT := obj.(type) {
+	case *Null:
+		return nil, nil
+	case *Integer:
+		return objT.Value, nil
+	case *Boolean:
+		return objT.Value, nil
+	case *String:
+		return objT.Value, nil
+	case *Array:
+		elements := make([]interface{}, len(objT.Elements))
+		for i, el := range objT.Elements {
+			value, err := natural(el)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = value
+		}
+		return elements, nil
+	case *Instance:
+		m := make(map[string]interface{}, len(objT.Fields))
+		for name, field := range objT.Fields {
+			value, err := natural(field)
+			if err != nil {
+				return nil, err
+			}
+			m[name] = value
+		}
+		return m, nil
+	case *Hash:
+		// 键全部为字符串时使用map[string]interface{}
+		allStrings := true
+		for _, pair := range objT.Pairs {
+			if pair.Key.Type() != STRING_OBJ {
+				allStrings = false
+				break
+			}
+		}
+		if allStrings {
+			m := make(map[string]interface{}, len(objT.Pairs))
+			for _, pair := range objT.Pairs {
+				value, err := natural(pair.Value)
+				if err != nil {
+					return nil, err
+				}
+				m[pair.Key.(*String).Value] = value
+			}
+			return m, nil
+		}
+		m := make(map[interface{}]interface{}, len(objT.Pairs))
+		for _, pair := range objT.Pairs {
+			key, err := natural(pair.Key)
+			if err != nil {
+				return nil, err
+			}
+			value, err := natural(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		}
+		return m, nil
+	default:
+		return obj, nil
+	}
+}
+
+func mismatch(obj Object, t reflect.Type) error {
+	return fmt.Errorf("cannot convert %s to %s", obj.Type(), t)
+}
+
+// 将Go函数封装为内置函数，参数和返回值通过反射转换
+// 如果最后一个返回值是error且不为nil，则返回错误对象
+func wrapFunc(fv reflect.Value) *Builtin {
+	ft := fv.Type()
+
+	return &Builtin{
+		Fn: func(args ...Object) Object {
+			numIn := ft.NumIn()
+			if ft.IsVariadic() {
+				if len(args) < numIn-1 {
+					return &Error{Message: fmt.Sprintf("wrong number of arguments. got=%d, want at least %d", len(args), numIn-1)}
+				}
+			} else if len(args) != numIn {
+				return &Error{Message: fmt.Sprintf("wrong number of arguments. got=%d, want=%d", len(args), numIn)}
+			}
+
+			in := make([]reflect.Value, len(args))
+			for i, arg := range args {
+				var argType reflect.Type
+				if ft.IsVariadic() && i >= numIn-1 {
+					argType = ft.In(numIn - 1).Elem()
+				} else {
+					argType = ft.In(i)
+				}
+
+				in[i] = reflect.New(argType).Elem()
+				if err := toValue(arg, in[i]); err != nil {
+					return &Error{Message: fmt.Sprintf("argument %d: %s", i+1, err)}
+				}
+			}
+
+			out := fv.Call(in)
+			if n := len(out); n > 0 && ft.Out(n-1) == errorType {
+				if err, _ := out[n-1].Interface().(error); err != nil {
+					return &Error{Message: err.Error()}
+				}
+				out = out[:n-1]
+			}
+
+			results := make([]Object, len(out))
+			for i, o := range out {
+				result, err := fromValue(o, visiting{})
+				if err != nil {
+					return &Error{Message: err.Error()}
+				}
+				results[i] = result
+			}
+
+			switch len(results) {
+			case 0:
+				return NULL
+			case 1:
+				return results[0]
+			default:
+				return &Array{Elements: results}
+			}
+		},
+	}
+}
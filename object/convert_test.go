@@ -0,0 +1,175 @@
+package object
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type point struct {
+	X    int
+	Y    int
+	Name string `monkey:"name"`
+}
+
+func TestFromGo(t *testing.T) {
+	tests := []struct {
+		input    interface{}
+		expected string
+	}{
+		{nil, "null"},
+		{5, "5"},
+		{uint8(7), "7"},
+		{3.0, "3"},
+		{"monkey", "monkey"},
+		{true, "true"},
+		{[]int{1, 2, 3}, "[1, 2, 3]"},
+		{[2]string{"a", "b"}, "[a, b]"},
+		{map[string]int{"one": 1}, "{one: 1}"},
+		{&point{X: 1}, ""},
+	}
+
+	for _, tt := range tests {
+		obj, err := FromGo(tt.input)
+		if err != nil {
+			t.Errorf("FromGo(%#v) returned error: %s", tt.input, err)
+			continue
+		}
+		if tt.expected != "" && obj.Inspect() != tt.expected {
+			t.Errorf("FromGo(%#v) wrong. want=%q, got=%q", tt.input, tt.expected, obj.Inspect())
+		}
+	}
+
+	if obj, _ := FromGo(false); obj != FALSE {
+		t.Errorf("false should be converted to FALSE. got=%+v", obj)
+	}
+
+	if _, err := FromGo(1.5); err == nil {
+		t.Errorf("expected error converting 1.5")
+	}
+	if _, err := FromGo(float64(1 << 63)); err == nil {
+		t.Errorf("expected error converting 2^63")
+	}
+
+	obj, err := FromGo(point{X: 1, Y: 2, Name: "p"})
+	if err != nil {
+		t.Fatalf("FromGo returned error: %s", err)
+	}
+	hash, ok := obj.(*Hash)
+	if !ok {
+		t.Fatalf("obj is not Hash. got=%T", obj)
+	}
+	pair, ok := hash.Pairs[(&String{Value: "name"}).HashKey()]
+	if !ok || pair.Value.Inspect() != "p" {
+		t.Errorf("struct tag not honoured. got=%+v", hash.Pairs)
+	}
+}
+
+type node struct {
+	Value int
+	Next  *node
+}
+
+func TestFromGoCycles(t *testing.T) {
+	n := &node{Value: 1}
+	n.Next = n
+
+	m := map[string]interface{}{}
+	m["self"] = m
+
+	s := []interface{}{nil}
+	s[0] = s
+
+	for _, v := range []interface{}{n, m, s} {
+		if _, err := FromGo(v); err == nil {
+			t.Errorf("expected error converting cyclic %T", v)
+		}
+	}
+
+	// 同一个值被多次引用但没有循环
+	shared := &node{Value: 2}
+	obj, err := FromGo([]*node{shared, shared})
+	if err != nil {
+		t.Fatalf("FromGo returned error: %s", err)
+	}
+	if len(obj.(*Array).Elements) != 2 {
+		t.Errorf("wrong result. got=%s", obj.Inspect())
+	}
+}
+
+func TestToGo(t *testing.T) {
+	arr := &Array{Elements: []Object{&Integer{Value: 1}, &Integer{Value: 2}}}
+
+	var ints []int
+	if err := ToGo(arr, &ints); err != nil {
+		t.Fatalf("ToGo returned error: %s", err)
+	}
+	if !reflect.DeepEqual(ints, []int{1, 2}) {
+		t.Errorf("wrong slice. got=%v", ints)
+	}
+
+	hash := &Hash{Pairs: map[HashKey]HashPair{}}
+	setHashPair(hash, &String{Value: "X"}, &Integer{Value: 3})
+	setHashPair(hash, &String{Value: "name"}, &String{Value: "p"})
+
+	var p point
+	if err := ToGo(hash, &p); err != nil {
+		t.Fatalf("ToGo returned error: %s", err)
+	}
+	if p != (point{X: 3, Name: "p"}) {
+		t.Errorf("wrong struct. got=%+v", p)
+	}
+
+	var value interface{}
+	if err := ToGo(hash, &value); err != nil {
+		t.Fatalf("ToGo returned error: %s", err)
+	}
+	if !reflect.DeepEqual(value, map[string]interface{}{"X": int64(3), "name": "p"}) {
+		t.Errorf("wrong natural value. got=%#v", value)
+	}
+
+	var s string
+	if err := ToGo(&Integer{Value: 1}, &s); err == nil {
+		t.Errorf("expected error converting INTEGER to string")
+	}
+
+	if err := ToGo(&Integer{Value: 1}, s); err == nil {
+		t.Errorf("expected error for non-pointer target")
+	}
+}
+
+func TestWrapFunc(t *testing.T) {
+	obj, err := FromGo(func(a, b int) int { return a + b })
+	if err != nil {
+		t.Fatalf("FromGo returned error: %s", err)
+	}
+	builtin, ok := obj.(*Builtin)
+	if !ok {
+		t.Fatalf("obj is not Builtin. got=%T", obj)
+	}
+
+	result := builtin.Fn(&Integer{Value: 1}, &Integer{Value: 2})
+	if result.Inspect() != "3" {
+		t.Errorf("wrong result. got=%s", result.Inspect())
+	}
+
+	result = builtin.Fn(&Integer{Value: 1})
+	if errObj, ok := result.(*Error); !ok || errObj.Message != "wrong number of arguments. got=1, want=2" {
+		t.Errorf("expected arity error. got=%+v", result)
+	}
+
+	failing, _ := FromGo(func(s ...string) (string, error) {
+		if len(s) == 0 {
+			return "", errors.New("no input")
+		}
+		return s[0], nil
+	})
+	result = failing.(*Builtin).Fn()
+	if errObj, ok := result.(*Error); !ok || errObj.Message != "no input" {
+		t.Errorf("expected error object. got=%+v", result)
+	}
+	result = failing.(*Builtin).Fn(&String{Value: "a"}, &String{Value: "b"})
+	if result.Inspect() != "a" {
+		t.Errorf("wrong result. got=%s", result.Inspect())
+	}
+}
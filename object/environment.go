@@ -1,13 +1,19 @@
 package object
 
+import (
+	"fmt"
+	"sort"
+)
+
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store  map[string]Object
+	consts map[string]bool // 当前作用域中由const声明的名称
+	outer  *Environment
 }
 
 func NewEnvironment() *Environment {
 	s := make(map[string]Object)
-	return &Environment{store: s}
+	return &Environment{store: s, consts: make(map[string]bool)}
 }
 
 // 环境扩展，变量作用域
@@ -26,7 +32,42 @@ func (e *Environment) Get(name string) (Object, bool) {
 	return obj, ok
 }
 
+// Set 在当前作用域绑定名称，当前作用域中的常量不会被覆盖，此时返回错误对象
+// 内层作用域可以遮蔽外层的常量
 func (e *Environment) Set(name string, val Object) Object {
+	if e.consts[name] {
+		return &Error{Message: fmt.Sprintf("cannot reassign constant `%s`", name)}
+	}
 	e.store[name] = val
 	return val
 }
+
+// SetConst 在当前作用域绑定一个不可变的名称
+func (e *Environment) SetConst(name string, val Object) Object {
+	result := e.Set(name, val)
+	if _, ok := result.(*Error); !ok {
+		e.consts[name] = true
+	}
+	return result
+}
+
+// IsConst 名称是否为当前作用域中的常量
+func (e *Environment) IsConst(name string) bool {
+	return e.consts[name]
+}
+
+// Declared 名称是否已在当前作用域中绑定，不搜索外层环境
+func (e *Environment) Declared(name string) bool {
+	_, ok := e.store[name]
+	return ok
+}
+
+// Names 当前作用域中绑定的名称，按字母顺序，不包括外层环境
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,21 @@
+package object
+
+import "testing"
+
+func TestEnvironmentConst(t *testing.T) {
+	env := NewEnvironment()
+	env.SetConst("x", &Integer{Value: 1})
+
+	result := env.Set("x", &Integer{Value: 2})
+	if _, ok := result.(*Error); !ok {
+		t.Errorf("Set should not overwrite a constant. got=%T", result)
+	}
+	if val, _ := env.Get("x"); val.Inspect() != "1" {
+		t.Errorf("constant was overwritten. got=%s", val.Inspect())
+	}
+
+	inner := NewEnclosedEnvironment(env)
+	if result := inner.Set("x", &Integer{Value: 3}); result.Inspect() != "3" {
+		t.Errorf("inner scope should shadow constant. got=%s", result.Inspect())
+	}
+}
@@ -24,6 +24,16 @@ const (
 	HASH_OBJ         = "HASH"
 	QUOTE_OBJ        = "QUOTE"
 	MACRO_OBJ        = "MACRO"
+	STRUCT_OBJ       = "STRUCT"
+	INSTANCE_OBJ     = "INSTANCE"
+)
+
+var (
+	// null只有一种
+	NULL = &Null{}
+	// bool只有两种可能，求值器依赖这两个引用来比较布尔值
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
 )
 
 // 所有值都会封装到一个符合Object接口的结构体中
@@ -116,22 +126,65 @@ func (rv *ReturnValue) Type() ObjectType {
 	return RETURN_VALUE_OBJ
 }
 
+// 错误种类，普通运行时错误为空字符串
+type ErrorKind string
+
+const (
+	RUNTIME_ERROR ErrorKind = ""
+	LIMIT_ERROR   ErrorKind = "LIMIT"  // 超出执行限制
+	CANCEL_ERROR  ErrorKind = "CANCEL" // 求值被context取消
+)
+
+// 调用栈中的一帧，记录被调用的函数以及调用发生的位置
+type StackFrame struct {
+	Function string
+	Line     int
+	Column   int
+	Elided   int // 被这一帧替换掉的尾调用帧的数量
+}
+
 // error
 type Error struct {
 	Message string
+	Kind    ErrorKind
+	Stack   []StackFrame // 错误发生时的调用栈，最外层的调用在前
+	Value   Object       // throw抛出的值，其他错误为nil
 }
 
 func (e *Error) Inspect() string {
 	return "ERROR: " + e.Message
 }
 
+// Traceback 输出调用栈以及错误信息
+func (e *Error) Traceback() string {
+	if len(e.Stack) == 0 {
+		return e.Inspect()
+	}
+
+	var out bytes.Buffer
+
+	out.WriteString("Traceback (most recent call last):\n")
+	for _, frame := range e.Stack {
+		if frame.Elided > 0 {
+			out.WriteString(fmt.Sprintf("  ... %d tail call(s) elided\n", frame.Elided))
+		}
+		out.WriteString(fmt.Sprintf("  line %d, column %d, in call to %s\n", frame.Line, frame.Column, frame.Function))
+	}
+	out.WriteString(e.Inspect())
+
+	return out.String()
+}
+
 func (e *Error) Type() ObjectType {
 	return ERROR_OBJ
 }
 
 // function
 type Function struct {
+	Name       string // let绑定时的名称，用于调用栈，匿名函数为空
 	Parameters []*ast.Identifier
+	Defaults   map[string]ast.Expression // 参数默认值，调用时在函数环境中求值
+	Rest       *ast.Identifier           // 剩余参数，接收多余的位置参数
 	Body       *ast.BlockStatement
 	Env        *Environment // 独立函数内部环境，因此可使用闭包
 }
@@ -139,14 +192,9 @@ type Function struct {
 func (f *Function) Inspect() string {
 	var out bytes.Buffer
 
-	params := []string{}
-	for _, p := range f.Parameters {
-		params = append(params, p.String())
-	}
-
 	out.WriteString("fn")
 	out.WriteString("(")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(ast.FormatParameters(f.Parameters, f.Defaults, f.Rest))
 	out.WriteString("){\n")
 	out.WriteString(f.Body.String())
 	out.WriteString("\n}")
@@ -242,6 +290,41 @@ func (h *Hash) Inspect() string {
 	return out.String()
 }
 
+// 用户定义的结构体类型 struct Point { x, y }，调用它创建实例
+type StructType struct {
+	Name   string
+	Fields []string
+}
+
+func (st *StructType) Type() ObjectType { return STRUCT_OBJ }
+
+func (st *StructType) Inspect() string {
+	return "struct " + st.Name + " { " + strings.Join(st.Fields, ", ") + " }"
+}
+
+// 结构体实例，字段按声明的顺序输出
+type Instance struct {
+	Struct *StructType
+	Fields map[string]Object
+}
+
+func (i *Instance) Type() ObjectType { return INSTANCE_OBJ }
+
+func (i *Instance) Inspect() string {
+	var out bytes.Buffer
+
+	fields := []string{}
+	for _, name := range i.Struct.Fields {
+		fields = append(fields, fmt.Sprintf("%s: %s", name, i.Fields[name].Inspect()))
+	}
+
+	out.WriteString(i.Struct.Name)
+	out.WriteString("{")
+	out.WriteString(strings.Join(fields, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+
 // 宏 对ast.Node进行封装
 // 不对其参数进行求值
 type Quote struct {
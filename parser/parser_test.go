@@ -17,6 +17,7 @@ func TestLetStatements(t *testing.T) {
 		{"let x = 5;", "x", 5},
 		{"let y = true;", "y", true},
 		{"let foobar = y;", "foobar", "y"},
+		{"let z = 10", "z", 10},
 	}
 
 	for _, tt := range tests {
@@ -42,6 +43,194 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestConstStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"const x = 5;", "const x = 5;"},
+		{"const [a, b] = arr;", "const [a, b] = arr;"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("stmt is not *ast.LetStatement. got=%T", program.Statements[0])
+		}
+		if !stmt.Constant() {
+			t.Errorf("stmt should be constant")
+		}
+		if stmt.String() != tt.expected {
+			t.Errorf("wrong string. want=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+}
+
+func TestStructStatementParsing(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedName   string
+		expectedFields []string
+	}{
+		{"struct Point { x, y }", "Point", []string{"x", "y"}},
+		{"struct Empty {};", "Empty", nil},
+		{"struct Pair { first, second, }", "Pair", []string{"first", "second"}},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has wrong number of statements. got=%d", len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.StructStatement)
+		if !ok {
+			t.Fatalf("stmt is not *ast.StructStatement. got=%T", program.Statements[0])
+		}
+		if stmt.Name.Value != tt.expectedName {
+			t.Errorf("wrong struct name. want=%q, got=%q", tt.expectedName, stmt.Name.Value)
+		}
+		if len(stmt.Fields) != len(tt.expectedFields) {
+			t.Fatalf("wrong number of fields. want=%d, got=%d", len(tt.expectedFields), len(stmt.Fields))
+		}
+		for i, field := range tt.expectedFields {
+			testIdentifier(t, stmt.Fields[i], field)
+		}
+	}
+
+	p := New(lexer.New("struct Point { x, x }"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "duplicate field x in struct Point" {
+		t.Errorf("wrong parser errors. got=%q", p.Errors())
+	}
+}
+
+func TestLetPatternParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b] = arr;", "let [a, b] = arr;"},
+		{"let [a, [b, c], ...rest] = arr;", "let [a, [b, c], ...rest] = arr;"},
+		{"let [] = arr;", "let [] = arr;"},
+		{"let {name, age} = person;", "let {name, age} = person;"},
+		{`let {"name": n, pos: [x, y], 1: one} = person;`, "let {name: n, pos: [x, y], 1: one} = person;"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("stmt is not *ast.LetStatement. got=%T", program.Statements[0])
+		}
+		if stmt.Name != nil || stmt.Pattern == nil {
+			t.Fatalf("let statement should have a pattern. got=%+v", stmt)
+		}
+		if stmt.String() != tt.expected {
+			t.Errorf("wrong string. want=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+
+	errorTests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"let [a, fn] = arr;", "unexpected FUNCTION in pattern"},
+		{"let [...rest, a] = arr;", "expected next token to be ], got , instead"},
+		{"let {[a]} = h;", "unexpected [ in pattern"},
+	}
+
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expectedError {
+			t.Errorf("wrong parser errors for %q. want=%q, got=%q", tt.input, tt.expectedError, p.Errors())
+		}
+	}
+}
+
+func TestMatchExpressionParsing(t *testing.T) {
+	input := `match (x) { 1 => "one", -1 => "neg", [a, ...r] if a > 0 => a, {name} => name, _ => 0 }`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MatchExpression. got=%T", stmt.Expression)
+	}
+	if !testIdentifier(t, exp.Subject, "x") {
+		return
+	}
+	if len(exp.Arms) != 5 {
+		t.Fatalf("wrong number of arms. want=5, got=%d", len(exp.Arms))
+	}
+	if exp.Arms[2].Guard == nil || exp.Arms[0].Guard != nil {
+		t.Errorf("guard not parsed correctly")
+	}
+
+	expected := "match (x) { 1 => one, (-1) => neg, [a, ...r] if (a > 0) => a, {name} => name, _ => 0 }"
+	if exp.String() != expected {
+		t.Errorf("wrong string. want=%q, got=%q", expected, exp.String())
+	}
+
+	p = New(lexer.New(`match (x) { 1 "one" }`))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "expected next token to be =>, got STRING instead" {
+		t.Errorf("wrong parser errors. got=%q", p.Errors())
+	}
+}
+
+func TestTryExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`try { f(); } catch (e) { e; }`, "try f() catch (e) e"},
+		{`try { f(); } finally { g(); }`, "try f() finally g()"},
+		{`try { f(); } catch (e) { 1; } finally { g(); }`, "try f() catch (e) 1 finally g()"},
+		{`throw "oops";`, `throw oops;`},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has wrong number of statements. got=%d", len(program.Statements))
+		}
+		if program.Statements[0].String() != tt.expected {
+			t.Errorf("wrong string. want=%q, got=%q", tt.expected, program.Statements[0].String())
+		}
+	}
+
+	stmt := New(lexer.New(`try { 1 } catch (err) { 2 }`)).ParseProgram().Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.TryExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.TryExpression. got=%T", stmt.Expression)
+	}
+	testIdentifier(t, exp.CatchParam, "err")
+
+	p := New(lexer.New(`try { 1 }`))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "try without catch or finally" {
+		t.Errorf("wrong parser errors. got=%q", p.Errors())
+	}
+}
+
 func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	if s.TokenLiteral() != "let" {
 		t.Errorf("s.TokenLiteral not 'let'. got=%q", s.TokenLiteral())
@@ -79,6 +268,22 @@ func checkParserErrors(t *testing.T, p *Parser) {
 	t.FailNow()
 }
 
+func TestOptionalSemicolons(t *testing.T) {
+	input := `let x = 1
+return x
+puts(x)`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	expected := "let x = 1;return x;puts(x)"
+	if program.String() != expected {
+		t.Errorf("wrong program. want=%q, got=%q", expected, program.String())
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input         string
@@ -87,6 +292,8 @@ func TestReturnStatements(t *testing.T) {
 		{"return 5;", 5},
 		{"return true;", true},
 		{"return foobar;", "foobar"},
+		// 最后一条语句可以省略分号
+		{"return 10", 10},
 	}
 
 	for _, tt := range tests {
@@ -306,6 +513,10 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 		{"add(a + b + c * d / f + g)", "add((((a + b) + ((c * d) / f)) + g))"},
 		{"a * [1, 2, 3, 4][b * c] * d", "((a * ([1, 2, 3, 4][(b * c)])) * d)"},
 		{"add(a * b[2], b[1], 2 * [1, 2][1])", "add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))"},
+		{"-a.b", "(-(a.b))"},
+		{"a.b.c + d", "(((a.b).c) + d)"},
+		{"a.b(c).d", "((a.b)(c).d)"},
+		{"a[0].b", "((a[0]).b)"},
 	}
 
 	for _, tt := range tests {
@@ -614,6 +825,79 @@ func TestFunctionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestFunctionDefaultAndRestParameterParsing(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedParams   []string
+		expectedDefaults map[string]string
+		expectedRest     string
+		expectedString   string
+	}{
+		{"fn(a, b = 10) {};", []string{"a", "b"}, map[string]string{"b": "10"}, "", "fn(a, b = 10)"},
+		{"fn(a, ...rest) {};", []string{"a"}, map[string]string{}, "rest", "fn(a, ...rest)"},
+		{"fn(a = 1 + 2, ...rest) {};", []string{"a"}, map[string]string{"a": "(1 + 2)"}, "rest", "fn(a = (1 + 2), ...rest)"},
+		{"fn(...rest) {};", []string{}, map[string]string{}, "rest", "fn(...rest)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function := stmt.Expression.(*ast.FunctionLiteral)
+
+		if len(function.Parameters) != len(tt.expectedParams) {
+			t.Fatalf("length parameters wrong. want %d, got=%d", len(tt.expectedParams), len(function.Parameters))
+		}
+		for i, ident := range tt.expectedParams {
+			testLiteralExpression(t, function.Parameters[i], ident)
+		}
+
+		if len(function.Defaults) != len(tt.expectedDefaults) {
+			t.Fatalf("length defaults wrong. want %d, got=%d", len(tt.expectedDefaults), len(function.Defaults))
+		}
+		for name, def := range tt.expectedDefaults {
+			if function.Defaults[name].String() != def {
+				t.Errorf("default of %s wrong. want=%q, got=%q", name, def, function.Defaults[name].String())
+			}
+		}
+
+		if tt.expectedRest == "" && function.Rest != nil {
+			t.Errorf("unexpected rest parameter %s", function.Rest.Value)
+		}
+		if tt.expectedRest != "" && (function.Rest == nil || function.Rest.Value != tt.expectedRest) {
+			t.Errorf("rest parameter wrong. want=%s, got=%+v", tt.expectedRest, function.Rest)
+		}
+
+		if function.String() != tt.expectedString {
+			t.Errorf("function.String() wrong. want=%q, got=%q", tt.expectedString, function.String())
+		}
+	}
+}
+
+func TestFunctionParameterErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"fn(a = 1, b) {}", "parameter b without default follows parameter with default"},
+		{"fn(...rest, a) {}", "expected next token to be ), got , instead"},
+		{"fn(1) {}", "expected parameter name, got INT instead"},
+		{"f(a: 1, 2)", "positional argument follows named argument"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expectedError {
+			t.Errorf("wrong parser errors for %q. want=%q, got=%q", tt.input, tt.expectedError, p.Errors())
+		}
+	}
+}
+
 func TestCallExpressionParsing(t *testing.T) {
 	input := "add(1, 2 * 3, 4 + 5);"
 
@@ -657,6 +941,7 @@ func TestCallExpressionParameterParsing(t *testing.T) {
 		input         string
 		expectedIdent string
 		expectedArgs  []string
+		expectedNamed []string
 	}{
 		{
 			input:         "add();",
@@ -673,6 +958,12 @@ func TestCallExpressionParameterParsing(t *testing.T) {
 			expectedIdent: "add",
 			expectedArgs:  []string{"1", "(2 * 3)", "(4 + 5)"},
 		},
+		{
+			input:         "add(1, b: 2 * 3, c: x);",
+			expectedIdent: "add",
+			expectedArgs:  []string{"1"},
+			expectedNamed: []string{"b: (2 * 3)", "c: x"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -703,6 +994,18 @@ func TestCallExpressionParameterParsing(t *testing.T) {
 					arg, exp.Arguments[i].String())
 			}
 		}
+
+		if len(exp.NamedArguments) != len(tt.expectedNamed) {
+			t.Fatalf("wrong number of named arguments. want=%d, got=%d",
+				len(tt.expectedNamed), len(exp.NamedArguments))
+		}
+
+		for i, arg := range tt.expectedNamed {
+			if exp.NamedArguments[i].String() != arg {
+				t.Errorf("named argument %d wrong. want=%q, got=%q", i,
+					arg, exp.NamedArguments[i].String())
+			}
+		}
 	}
 }
 
@@ -833,7 +1136,8 @@ func TestParsingHashLiteralsStringKeys(t *testing.T) {
 		t.Errorf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
 	}
 
-	for key, value := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		key, value := pair.Key, pair.Value
 		literal, ok := key.(*ast.StringLiteral)
 		if !ok {
 			t.Errorf("key is not ast.StringLiteral. got=%T", key)
@@ -868,7 +1172,8 @@ func TestParsingHashLiteralsBooleanKeys(t *testing.T) {
 		t.Errorf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
 	}
 
-	for key, value := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		key, value := pair.Key, pair.Value
 		boolean, ok := key.(*ast.Boolean)
 		if !ok {
 			t.Errorf("key is not ast.BooleanLiteral. got=%T", key)
@@ -904,7 +1209,8 @@ func TestParsingHashLiteralsIntegerKeys(t *testing.T) {
 		t.Errorf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
 	}
 
-	for key, value := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		key, value := pair.Key, pair.Value
 		integer, ok := key.(*ast.IntegerLiteral)
 		if !ok {
 			t.Errorf("key is not ast.IntegerLiteral. got=%T", key)
@@ -947,7 +1253,8 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 		},
 	}
 
-	for key, value := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		key, value := pair.Key, pair.Value
 		literal, ok := key.(*ast.StringLiteral)
 		if !ok {
 			t.Errorf("key is not ast.StringLiteral. got=%T", key)
@@ -19,7 +19,7 @@ func tracePrint(fs string) {
 	// 开启
 	// fmt.Printf("%s%s\n", identLevel(), fs)
 	// 关闭
-	fmt.Sprintf("%s%s\n", identLevel(), fs)
+	_ = fmt.Sprintf("%s%s\n", identLevel(), fs)
 }
 
 func incIdent() { traceLevel = traceLevel + 1 }
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"os"
 
 	"monkey/evaluator"
 	"monkey/lexer"
@@ -23,6 +24,8 @@ func Start(in io.Reader, out io.Writer) {
 	scanner := bufio.NewScanner(in)
 	env := object.NewEnvironment()
 	macroEnv := object.NewEnclosedEnvironment(env)
+	eval := evaluator.New()
+	eval.SetModuleLoader(readModule)
 
 	for {
 		fmt.Fprintf(out, PROMPT)
@@ -41,11 +44,18 @@ func Start(in io.Reader, out io.Writer) {
 		}
 
 		// 插入宏扩展
-		evaluator.DefineMacros(program, macroEnv)
-		expended := evaluator.ExpandMacros(program, macroEnv)
+		program = evaluator.DefineMacros(program, macroEnv)
+		expended, diagnostics := eval.ExpandMacros(program, macroEnv)
+		if len(diagnostics) != 0 {
+			printMacroErrors(out, diagnostics)
+			continue
+		}
 
-		evaluated := evaluator.Eval(expended, env)
-		if evaluated != nil {
+		evaluated := eval.Eval(expended, env)
+		if errObj, ok := evaluated.(*object.Error); ok {
+			io.WriteString(out, errObj.Traceback())
+			io.WriteString(out, "\n")
+		} else if evaluated != nil {
 			io.WriteString(out, evaluated.Inspect())
 			io.WriteString(out, "\n")
 		}
@@ -73,3 +83,18 @@ func printParserErrors(out io.Writer, errors []string) {
 		io.WriteString(out, "\t"+msg+"\n")
 	}
 }
+
+func printMacroErrors(out io.Writer, diagnostics []evaluator.Diagnostic) {
+	io.WriteString(out, MONKEY_FACE)
+	io.WriteString(out, "Woops! We ran into some monkey business here!\n")
+	io.WriteString(out, " macro expansion errors:\n")
+	for _, d := range diagnostics {
+		io.WriteString(out, "\t"+d.String()+"\n")
+	}
+}
+
+// 从文件系统读取import的模块
+func readModule(path string) (string, error) {
+	src, err := os.ReadFile(path)
+	return string(src), err
+}
@@ -5,6 +5,8 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+	Line    int // 词法单元在源代码中的行号，从1开始
+	Column  int // 词法单元在源代码中的列号，从1开始
 }
 
 // 将语言关键字和用户自定义标识符区分